@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/klauspost/compress/zstd"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -29,6 +30,8 @@ type Data struct {
 	RawData     []byte `xml:",innerxml"`
 	// DataTiles is only used when layer encoding is XML.
 	DataTiles []*DataTile `xml:"tile"`
+	// Chunks is only used when the map is infinite.
+	Chunks []*Chunk `xml:"chunk"`
 }
 
 func (d *Data) String() string {
@@ -57,6 +60,17 @@ func (d *Data) decodeBase64() (data []byte, err error) {
 		if err != nil {
 			return
 		}
+	case "zstd":
+		log.Debug("decodeBase64: compression is zstd")
+
+		var zr *zstd.Decoder
+		zr, err = zstd.NewReader(encr, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return
+		}
+		defer zr.Close()
+
+		comr = zr
 	case "":
 		log.Debug("decodeBase64: no compression")
 
@@ -93,3 +107,36 @@ func (d *Data) decodeCSV() ([]GID, error) {
 	}
 	return gids, nil
 }
+
+// Chunk is a TMX file structure holding a section of an infinite maps' layer data.  The chunk is encoded with the
+// encoding and compression of the Data which contains it.
+type Chunk struct {
+	// X and Y are the tile co-ordinates of the top-left of the chunk.  These may be negative.
+	X int `xml:"x,attr"`
+	Y int `xml:"y,attr"`
+	// Width and Height are the number of tiles in the chunk - not the size in pixels.
+	Width     int         `xml:"width,attr"`
+	Height    int         `xml:"height,attr"`
+	RawData   []byte      `xml:",innerxml"`
+	DataTiles []*DataTile `xml:"tile"`
+	// DecodedTiles holds the tiles contained in the chunk.  Tile entry at (x,y), relative to the chunk origin, is
+	// obtained using c.DecodedTiles[y*c.Width+x].
+	DecodedTiles []*DecodedTile
+
+	gids []GID
+}
+
+func (c *Chunk) String() string {
+	return fmt.Sprintf("Chunk{Origin: (%d, %d), Size: %dx%d}", c.X, c.Y, c.Width, c.Height)
+}
+
+// data will return the chunk as a Data structure, so the chunk can be decoded in the same way as the data of a finite
+// layer.
+func (c *Chunk) data(parent *Data) *Data {
+	return &Data{
+		Encoding:    parent.Encoding,
+		Compression: parent.Compression,
+		RawData:     c.RawData,
+		DataTiles:   c.DataTiles,
+	}
+}
@@ -8,6 +8,7 @@ import (
 	_ "image/png"
 
 	"github.com/bcvery1/tilepix"
+	_ "github.com/bcvery1/tilepix/pixelgl"
 
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/pixelgl"
@@ -42,7 +43,8 @@ func run() {
 	for !win.Closed() {
 		win.Clear(color.White)
 
-		dt := time.Since(last).Seconds()
+		elapsed := time.Since(last)
+		dt := elapsed.Seconds()
 		last = time.Now()
 
 		cam := pixel.IM.Scaled(camPos.Add(winBounds.Center()), camZoom).Moved(pixel.ZV.Sub(camPos))
@@ -62,7 +64,8 @@ func run() {
 		}
 		camZoom *= math.Pow(camZoomSpeed, win.MouseScroll().Y)
 
-		m.DrawAll(win, color.Transparent, pixel.IM) // nolint: errcheck
+		m.Update(elapsed)
+		m.DrawAllWithCamera(win, color.Transparent, pixel.IM, camPos.Add(winBounds.Center())) // nolint: errcheck
 
 		win.Update()
 	}
@@ -6,6 +6,7 @@ import (
 	_ "image/png"
 
 	"github.com/bcvery1/tilepix"
+	_ "github.com/bcvery1/tilepix/pixelgl"
 
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/pixelgl"
@@ -1,18 +1,23 @@
 module github.com/bcvery1/tilepix
 
-go 1.12
+go 1.22
 
 require (
-	github.com/faiface/glhf v0.0.0-20181018222622-82a6317ac380 // indirect
 	github.com/faiface/mainthread v0.0.0-20171120011319-8b78f0a41ae3
 	github.com/faiface/pixel v0.8.1-0.20190416082708-9aca3bfe7af3
+	github.com/klauspost/compress v1.18.0
+	github.com/sirupsen/logrus v1.4.1
+)
+
+require (
+	github.com/faiface/glhf v0.0.0-20181018222622-82a6317ac380 // indirect
 	github.com/go-gl/gl v0.0.0-20190320180904-bf2b1f2f34d7 // indirect
 	github.com/go-gl/glfw v0.0.0-20190409004039-e6da0acd62b1 // indirect
 	github.com/go-gl/mathgl v0.0.0-20190415092908-39e6cc4dcc59 // indirect
 	github.com/konsorten/go-windows-terminal-sequences v1.0.2 // indirect
 	github.com/pkg/errors v0.8.1 // indirect
-	github.com/sirupsen/logrus v1.4.1
 	github.com/stretchr/objx v0.2.0 // indirect
 	golang.org/x/crypto v0.0.0-20190411191339-88737f569e3a // indirect
+	golang.org/x/image v0.0.0-20190321063152-3fc05d484e9f // indirect
 	golang.org/x/sys v0.0.0-20190415145633-3fd5a3612ccd // indirect
 )
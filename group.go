@@ -0,0 +1,242 @@
+package tilepix
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/faiface/pixel"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+   ___
+  / __|_ _ ___ _  _ _ __
+ | (_ | '_/ _ \ || | '_ \
+  \___|_| \___/\_,_| .__/
+                   |_|
+*/
+
+// Group is a TMX file structure holding a Tiled group layer.  Groups contain other layers, including further groups,
+// and their offset, opacity, visibility and properties apply to all of the layers they contain.
+type Group struct {
+	Name    string  `xml:"name,attr"`
+	OffSetX float64 `xml:"offsetx,attr"`
+	OffSetY float64 `xml:"offsety,attr"`
+	Opacity float32 `xml:"opacity,attr"`
+	Visible bool    `xml:"visible,attr"`
+	// TintColor is the Tiled colour string the layers in the group are multiplied by, empty if the group is not
+	// tinted.
+	TintColor string `xml:"tintcolor,attr"`
+	// ParallaxX and ParallaxY are the factors the layers in the group scroll by, relative to the camera.  These are
+	// multiplied by the factors of each layer.
+	ParallaxX    float64        `xml:"parallaxx,attr"`
+	ParallaxY    float64        `xml:"parallaxy,attr"`
+	Properties   []*Property    `xml:"properties>property"`
+	TileLayers   []*TileLayer   `xml:"-"`
+	ObjectGroups []*ObjectGroup `xml:"-"`
+	ImageLayers  []*ImageLayer  `xml:"-"`
+	Groups       []*Group       `xml:"-"`
+
+	// layers holds the layers in the group in the order they appear in the map.
+	layers []Layer
+	// group is the group which contains this group, nil if it is at the top level of the map.
+	group *Group
+
+	// parentMap is the map which contains this object
+	parentMap *Map
+}
+
+// GetName returns the name of the group.
+func (g *Group) GetName() string {
+	return g.Name
+}
+
+// GetProperty returns the Groups' Property by its name, or nil if there is no such property.  Properties are inherited
+// from the groups which contain this group.
+func (g *Group) GetProperty(name string) *Property {
+	if g == nil {
+		return nil
+	}
+
+	if p := getProperty(g.Properties, name); p != nil {
+		return p
+	}
+	return g.group.GetProperty(name)
+}
+
+// IsVisible returns whether the group is visible.  A group is only visible if all of the groups which contain it are
+// visible.
+func (g *Group) IsVisible() bool {
+	if g == nil {
+		return true
+	}
+
+	return g.Visible && g.group.IsVisible()
+}
+
+// Layers returns the layers within the group, in the order they appear in the map.
+func (g *Group) Layers() []Layer {
+	return orderLayers(g.layers, g.TileLayers, g.ObjectGroups, g.ImageLayers, g.Groups)
+}
+
+// Parent returns the group which contains this group, or nil if it is at the top level of the map.
+func (g *Group) Parent() *Group {
+	return g.group
+}
+
+func (g *Group) String() string {
+	return fmt.Sprintf(
+		"Group{Name: '%s', Properties: %v, TileLayers: %v, Object layers: %v, Image layers: %v, Groups: %v}",
+		g.Name,
+		g.Properties,
+		g.TileLayers,
+		g.ObjectGroups,
+		g.ImageLayers,
+		g.Groups,
+	)
+}
+
+// TotalOffset returns the offset of the group, combined with the offsets of the groups which contain it.  The offset is
+// in game co-ordinates, so the Y component is negated from the offset set in Tiled.
+func (g *Group) TotalOffset() pixel.Vec {
+	if g == nil {
+		return pixel.ZV
+	}
+
+	return pixel.V(g.OffSetX, -g.OffSetY).Add(g.group.TotalOffset())
+}
+
+// TotalOpacity returns the opacity of the group, multiplied by the opacity of the groups which contain it.
+func (g *Group) TotalOpacity() float64 {
+	if g == nil {
+		return 1
+	}
+
+	return float64(g.Opacity) * g.group.TotalOpacity()
+}
+
+// TotalParallax returns the parallax factors of the group, multiplied by the parallax factors of the groups which
+// contain it.
+func (g *Group) TotalParallax() pixel.Vec {
+	if g == nil {
+		return pixel.V(1, 1)
+	}
+
+	return pixel.V(g.ParallaxX, g.ParallaxY).ScaledXY(g.group.TotalParallax())
+}
+
+// TotalTint returns the tint colour of the group, multiplied by the tint colours of the groups which contain it.
+func (g *Group) TotalTint() pixel.RGBA {
+	if g == nil {
+		return pixel.Alpha(1)
+	}
+
+	return tintColor(g.TintColor).Mul(g.group.TotalTint())
+}
+
+// UnmarshalXML decodes a group layer.  Tiled omits the opacity, visible and parallax attributes when they are the
+// default values, so these are set before decoding.  The layers in the group are decoded in the order they appear, which
+// is kept for `Group.Layers`.
+func (g *Group) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type group Group
+	raw := struct {
+		*group
+		Layers []*layerElement `xml:",any"`
+	}{group: &group{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1}}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		log.WithError(err).Error("Group.UnmarshalXML: could not decode group")
+		return err
+	}
+
+	*g = Group(*raw.group)
+	g.layers = layersOf(raw.Layers)
+	g.TileLayers, g.ObjectGroups, g.ImageLayers, g.Groups = splitLayers(g.layers)
+	return nil
+}
+
+// allTileLayers returns the tile layers in the group, including those nested in further groups.
+func (g *Group) allTileLayers() []*TileLayer {
+	ls := append([]*TileLayer(nil), g.TileLayers...)
+	for _, cg := range g.Groups {
+		ls = append(ls, cg.allTileLayers()...)
+	}
+	return ls
+}
+
+// allObjectGroups returns the object groups in the group, including those nested in further groups.
+func (g *Group) allObjectGroups() []*ObjectGroup {
+	ogs := append([]*ObjectGroup(nil), g.ObjectGroups...)
+	for _, cg := range g.Groups {
+		ogs = append(ogs, cg.allObjectGroups()...)
+	}
+	return ogs
+}
+
+// allImageLayers returns the image layers in the group, including those nested in further groups.
+func (g *Group) allImageLayers() []*ImageLayer {
+	ils := append([]*ImageLayer(nil), g.ImageLayers...)
+	for _, cg := range g.Groups {
+		ils = append(ils, cg.allImageLayers()...)
+	}
+	return ils
+}
+
+// setGroups will set the group of each layer contained in this group, and all nested groups.
+func (g *Group) setGroups() {
+	for _, l := range g.TileLayers {
+		l.group = g
+	}
+	for _, og := range g.ObjectGroups {
+		og.group = g
+	}
+	for _, im := range g.ImageLayers {
+		im.group = g
+	}
+	for _, cg := range g.Groups {
+		cg.group = g
+		cg.setGroups()
+	}
+}
+
+func (g *Group) setParent(m *Map) {
+	g.parentMap = m
+
+	for _, p := range g.Properties {
+		p.setParent(m)
+	}
+	for _, l := range g.TileLayers {
+		l.setParent(m)
+	}
+	for _, og := range g.ObjectGroups {
+		og.setParent(m)
+	}
+	for _, im := range g.ImageLayers {
+		im.setParent(m)
+	}
+	for _, cg := range g.Groups {
+		cg.setParent(m)
+	}
+}
+
+// findGroup will find the nested group by the path of group names, separated by '/'.
+func findGroup(groups []*Group, path string) *Group {
+	name, rest := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		name, rest = path[:i], path[i+1:]
+	}
+
+	for _, g := range groups {
+		if g.Name != name {
+			continue
+		}
+		if rest == "" {
+			return g
+		}
+		if found := findGroup(g.Groups, rest); found != nil {
+			return found
+		}
+	}
+	return nil
+}
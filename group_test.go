@@ -0,0 +1,30 @@
+package tilepix
+
+import "testing"
+
+func TestGroup_String(t *testing.T) {
+	type fields struct {
+		Name string
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		want   string
+	}{
+		{
+			name:   "Basic string",
+			fields: fields{Name: "name_g"},
+			want:   "Group{Name: 'name_g', Properties: [], TileLayers: [], Object layers: [], Image layers: [], Groups: []}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &Group{
+				Name: tt.fields.Name,
+			}
+			if got := g.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package tilepix
 
 import (
+	"bytes"
 	"fmt"
 	"path/filepath"
 
@@ -17,12 +18,18 @@ import (
                 |___/
 */
 
-// Image is a TMX file structure which referencing an image file, with associated properies.
+// Image is a TMX file structure which referencing an image file, with associated properies.  Images may instead be
+// embedded in the TMX file, in which case Source is empty and the image is held in Data.
 type Image struct {
 	Source string `xml:"source,attr"`
 	Trans  string `xml:"trans,attr"`
 	Width  int    `xml:"width,attr"`
 	Height int    `xml:"height,attr"`
+	// Format is the file format of an embedded image, such as "png".
+	Format string `xml:"format,attr"`
+	// Data holds the base64 encoded, and optionally compressed, file of an embedded image.  This is nil unless the
+	// image is embedded.
+	Data *Data `xml:"data"`
 
 	sprite  *pixel.Sprite
 	picture pixel.Picture
@@ -35,16 +42,65 @@ func (i *Image) String() string {
 	return fmt.Sprintf("Image{Source: %s, Size: %dx%d}", i.Source, i.Width, i.Height)
 }
 
+// clone returns a copy of the image, or nil if the image is nil.
+func (i *Image) clone() *Image {
+	if i == nil {
+		return nil
+	}
+
+	c := *i
+	c.parentMap = nil
+	return &c
+}
+
 func (i *Image) initSprite() error {
+	return i.loadSprite(i.parentMap.dir)
+}
+
+// loadSprite will load the image, relative to dir, if it has not already been loaded.  The image is loaded with the
+// loader of the map which contains it, so maps read with the same loader share the picture.  Embedded images are
+// decoded from their data instead.
+func (i *Image) loadSprite(dir string) error {
 	if i.sprite != nil {
 		return nil
 	}
 
-	log.WithFields(log.Fields{"Path": i.Source, "Width": i.Width, "Height": i.Height}).Debug("Image.initSprite: loading sprite")
+	if i.Data != nil {
+		return i.loadEmbeddedSprite()
+	}
+
+	log.WithFields(log.Fields{"Path": i.Source, "Width": i.Width, "Height": i.Height}).Debug("Image.loadSprite: loading sprite")
+
+	pic, err := i.parentMap.getLoader().picture(filepath.Join(dir, i.Source))
+	if err != nil {
+		log.WithError(err).Error("Image.loadSprite: could not load picture")
+		return err
+	}
+
+	i.sprite = pixel.NewSprite(pic, pic.Bounds())
+	i.picture = pic
+
+	return nil
+}
+
+// loadEmbeddedSprite will decode the image from its' data.  Tiled only embeds base64 encoded images.
+func (i *Image) loadEmbeddedSprite() error {
+	log.WithFields(log.Fields{"Format": i.Format, "Width": i.Width, "Height": i.Height}).Debug("Image.loadEmbeddedSprite: loading sprite")
+
+	if i.Data.Encoding != "base64" {
+		log.WithError(ErrUnknownEncoding).WithField("Encoding", i.Data.Encoding).Error("Image.loadEmbeddedSprite: unable to handle this encoding type")
+		return ErrUnknownEncoding
+	}
+
+	b, err := i.Data.decodeBase64()
+	if err != nil {
+		log.WithError(err).Error("Image.loadEmbeddedSprite: could not decode image data")
+		return err
+	}
 
-	sprite, pictureData, err := loadSpriteFromFile(filepath.Join(i.parentMap.dir, i.Source))
+	sprite, pictureData, err := loadSprite(bytes.NewReader(b))
 	if err != nil {
-		log.WithError(err).Error("Image.initSprite: could not load sprite from file")
+		log.WithError(err).Error("Image.loadEmbeddedSprite: could not load sprite from data")
 		return err
 	}
 
@@ -1,7 +1,14 @@
 package tilepix
 
 import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	_ "image/png"
+	"os"
 	"testing"
+
+	"github.com/faiface/pixel"
 )
 
 func TestImage_String(t *testing.T) {
@@ -34,3 +41,55 @@ func TestImage_String(t *testing.T) {
 		})
 	}
 }
+
+func TestImage_loadSprite_embedded(t *testing.T) {
+	white, err := os.ReadFile("testdata/singleWhite.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(white); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    *Data
+		wantErr error
+	}{
+		{
+			name: "base64",
+			data: &Data{Encoding: "base64", RawData: []byte(base64.StdEncoding.EncodeToString(white))},
+		},
+		{
+			name: "base64-zlib",
+			data: &Data{Encoding: "base64", Compression: "zlib", RawData: []byte(base64.StdEncoding.EncodeToString(compressed.Bytes()))},
+		},
+		{
+			name:    "unknown encoding",
+			data:    &Data{Encoding: "csv"},
+			wantErr: ErrUnknownEncoding,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The directory does not exist, so the image must be loaded from its' data.
+			i := &Image{Format: "png", Data: tt.data}
+			if err := i.loadSprite("missing"); err != tt.wantErr {
+				t.Fatalf("loadSprite() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got := i.picture.Bounds().Size(); !got.Eq(pixel.V(32, 32)) {
+				t.Errorf("Expected a 32x32 picture, got %v", got)
+			}
+		})
+	}
+}
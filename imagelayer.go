@@ -1,6 +1,7 @@
 package tilepix
 
 import (
+	"encoding/xml"
 	"fmt"
 
 	"github.com/faiface/pixel"
@@ -23,7 +24,18 @@ type ImageLayer struct {
 	OffSetX float64 `xml:"offsetx,attr"`
 	OffSetY float64 `xml:"offsety,attr"`
 	Opacity float64 `xml:"opacity,attr"`
-	Image   *Image  `xml:"image"`
+	Visible bool    `xml:"visible,attr"`
+	// TintColor is the Tiled colour string the image is multiplied by, empty if the layer is not tinted.
+	TintColor string `xml:"tintcolor,attr"`
+	// ParallaxX and ParallaxY are the factors the layer scrolls by, relative to the camera.  A factor of 1 scrolls with
+	// the map, lower factors scroll slower so the image appears further away.
+	ParallaxX  float64     `xml:"parallaxx,attr"`
+	ParallaxY  float64     `xml:"parallaxy,attr"`
+	Properties []*Property `xml:"properties>property"`
+	Image      *Image      `xml:"image"`
+
+	// group is the group which contains this layer, nil if it is at the top level of the map.
+	group *Group
 
 	// parentMap is the map which contains this object
 	parentMap *Map
@@ -36,21 +48,100 @@ func (im *ImageLayer) Draw(target pixel.Target, mat pixel.Matrix) error {
 		return err
 	}
 
+	im.Image.sprite.DrawColorMask(target, im.matrix(mat), im.colorMask())
+	return nil
+}
+
+// matrix returns the matrix the layers' image is drawn with, shifted with the provided matrix.
+func (im *ImageLayer) matrix(mat pixel.Matrix) pixel.Matrix {
 	// Shift image right-down by half its' dimensions.
-	// Shift image by layer offset.
-	mat = mat.Moved(pixel.V(float64(im.Image.Width/2), float64(im.Image.Height/-2))).Moved(pixel.V(im.OffSetX, -im.OffSetY))
+	// Shift image by layer offset, including the offsets of any groups containing the layer.
+	return mat.Moved(pixel.V(float64(im.Image.Width/2), float64(im.Image.Height/-2))).Moved(im.TotalOffset())
+}
 
-	im.Image.sprite.Draw(target, mat)
-	return nil
+// bounds returns the area, in game co-ordinates, the layers' image is drawn to with the provided matrix.
+func (im *ImageLayer) bounds(mat pixel.Matrix) pixel.Rect {
+	size := pixel.V(float64(im.Image.Width), float64(im.Image.Height))
+	return pixel.Rect{Max: size}.Moved(im.matrix(mat).Project(pixel.ZV).Sub(size.Scaled(0.5)))
+}
+
+// colorMask returns the colour the layers' image is drawn with; the tint of the layer, faded by its' opacity.
+func (im *ImageLayer) colorMask() pixel.RGBA {
+	return im.TotalTint().Scaled(im.TotalOpacity())
+}
+
+// GetName returns the name of the layer.
+func (im *ImageLayer) GetName() string {
+	return im.Name
+}
+
+// GetProperty returns the ImageLayers' Property by its name, or nil if there is no such property.  Properties are
+// inherited from the groups which contain this layer.
+func (im *ImageLayer) GetProperty(name string) *Property {
+	if p := getProperty(im.Properties, name); p != nil {
+		return p
+	}
+	return im.group.GetProperty(name)
+}
+
+// IsVisible returns whether the layer is visible.  A layer is only visible if all of the groups which contain it are
+// visible.
+func (im *ImageLayer) IsVisible() bool {
+	return im.Visible && im.group.IsVisible()
+}
+
+// Parent returns the group which contains this layer, or nil if it is at the top level of the map.
+func (im *ImageLayer) Parent() *Group {
+	return im.group
 }
 
 func (im *ImageLayer) String() string {
 	return fmt.Sprintf("ImageLayer{Name: '%s', Image: %s}", im.Name, im.Image)
 }
 
+// TotalOffset returns the offset of the layer, combined with the offsets of the groups which contain it.  The offset is
+// in game co-ordinates, so the Y component is negated from the offset set in Tiled.
+func (im *ImageLayer) TotalOffset() pixel.Vec {
+	return pixel.V(im.OffSetX, -im.OffSetY).Add(im.group.TotalOffset())
+}
+
+// TotalOpacity returns the opacity of the layer, multiplied by the opacity of the groups which contain it.
+func (im *ImageLayer) TotalOpacity() float64 {
+	return im.Opacity * im.group.TotalOpacity()
+}
+
+// TotalParallax returns the parallax factors of the layer, multiplied by the parallax factors of the groups which
+// contain it.
+func (im *ImageLayer) TotalParallax() pixel.Vec {
+	return pixel.V(im.ParallaxX, im.ParallaxY).ScaledXY(im.group.TotalParallax())
+}
+
+// TotalTint returns the tint colour of the layer, multiplied by the tint colours of the groups which contain it.
+func (im *ImageLayer) TotalTint() pixel.RGBA {
+	return tintColor(im.TintColor).Mul(im.group.TotalTint())
+}
+
+// UnmarshalXML decodes an image layer.  Tiled omits the opacity, visible and parallax attributes when they are the
+// default values, so these are set before decoding.
+func (im *ImageLayer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type imageLayer ImageLayer
+	raw := imageLayer{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		log.WithError(err).Error("ImageLayer.UnmarshalXML: could not decode image layer")
+		return err
+	}
+
+	*im = ImageLayer(raw)
+	return nil
+}
+
 func (im *ImageLayer) setParent(m *Map) {
 	im.parentMap = m
 
+	for _, p := range im.Properties {
+		p.setParent(m)
+	}
+
 	if im.Image != nil {
 		im.Image.setParent(m)
 	}
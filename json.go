@@ -0,0 +1,614 @@
+package tilepix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The structures in this file mirror the Tiled JSON map format.  They are only used for decoding, after which they are
+// converted to the same structures produced when reading TMX; so the rest of the package behaves identically regardless
+// of the source format.
+
+type jsonMap struct {
+	Version         json.RawMessage `json:"version"`
+	Orientation     string          `json:"orientation"`
+	Width           int             `json:"width"`
+	Height          int             `json:"height"`
+	TileWidth       int             `json:"tilewidth"`
+	TileHeight      int             `json:"tileheight"`
+	RenderOrder     string          `json:"renderorder"`
+	StaggerAxis     string          `json:"staggeraxis"`
+	StaggerIndex    string          `json:"staggerindex"`
+	HexSideLength   int             `json:"hexsidelength"`
+	BackgroundColor string          `json:"backgroundcolor"`
+	ParallaxOriginX float64         `json:"parallaxoriginx"`
+	ParallaxOriginY float64         `json:"parallaxoriginy"`
+	Infinite        bool            `json:"infinite"`
+	Properties      []*jsonProperty `json:"properties"`
+	Tilesets        []*jsonTileset  `json:"tilesets"`
+	Layers          []*jsonLayer    `json:"layers"`
+}
+
+type jsonProperty struct {
+	Name         string          `json:"name"`
+	Type         string          `json:"type"`
+	PropertyType string          `json:"propertytype"`
+	Value        json.RawMessage `json:"value"`
+}
+
+type jsonTileset struct {
+	FirstGID         GID             `json:"firstgid"`
+	Source           string          `json:"source"`
+	Name             string          `json:"name"`
+	TileWidth        int             `json:"tilewidth"`
+	TileHeight       int             `json:"tileheight"`
+	Spacing          int             `json:"spacing"`
+	Margin           int             `json:"margin"`
+	TileCount        int             `json:"tilecount"`
+	Columns          int             `json:"columns"`
+	Image            string          `json:"image"`
+	ImageWidth       int             `json:"imagewidth"`
+	ImageHeight      int             `json:"imageheight"`
+	TransparentColor string          `json:"transparentcolor"`
+	ObjectAlignment  string          `json:"objectalignment"`
+	TileOffset       *jsonTileOffset `json:"tileoffset"`
+	Grid             *jsonGrid       `json:"grid"`
+	Properties       []*jsonProperty `json:"properties"`
+	Tiles            []*jsonTile     `json:"tiles"`
+}
+
+type jsonTileOffset struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type jsonGrid struct {
+	Orientation string `json:"orientation"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+}
+
+type jsonTile struct {
+	ID          ID              `json:"id"`
+	Image       string          `json:"image"`
+	ImageWidth  int             `json:"imagewidth"`
+	ImageHeight int             `json:"imageheight"`
+	ObjectGroup *jsonLayer      `json:"objectgroup"`
+	Properties  []*jsonProperty `json:"properties"`
+	Animation   []*jsonFrame    `json:"animation"`
+}
+
+type jsonFrame struct {
+	TileID   ID  `json:"tileid"`
+	Duration int `json:"duration"`
+}
+
+// jsonLayer holds any type of Tiled layer, distinguished by the Type field.
+type jsonLayer struct {
+	Type      string  `json:"type"`
+	Name      string  `json:"name"`
+	Visible   bool    `json:"visible"`
+	Locked    bool    `json:"locked"`
+	Opacity   float64 `json:"opacity"`
+	OffSetX   float64 `json:"offsetx"`
+	OffSetY   float64 `json:"offsety"`
+	TintColor string  `json:"tintcolor"`
+	// ParallaxX and ParallaxY are omitted by Tiled when they are 1, so are pointers to tell this from zero.
+	ParallaxX  *float64        `json:"parallaxx"`
+	ParallaxY  *float64        `json:"parallaxy"`
+	Properties []*jsonProperty `json:"properties"`
+
+	// Tile layer fields.
+	Data        json.RawMessage `json:"data"`
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+	Chunks      []*jsonChunk    `json:"chunks"`
+
+	// Object group fields.
+	Color   string        `json:"color"`
+	Objects []*jsonObject `json:"objects"`
+
+	// Image layer fields.
+	Image       string `json:"image"`
+	ImageWidth  int    `json:"imagewidth"`
+	ImageHeight int    `json:"imageheight"`
+
+	// Group fields.
+	Layers []*jsonLayer `json:"layers"`
+}
+
+type jsonChunk struct {
+	X      int             `json:"x"`
+	Y      int             `json:"y"`
+	Width  int             `json:"width"`
+	Height int             `json:"height"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type jsonObject struct {
+	ID         ID              `json:"id"`
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	X          float64         `json:"x"`
+	Y          float64         `json:"y"`
+	Width      float64         `json:"width"`
+	Height     float64         `json:"height"`
+	GID        ID              `json:"gid"`
+	Visible    *bool           `json:"visible"`
+	Ellipse    bool            `json:"ellipse"`
+	Point      bool            `json:"point"`
+	Polygon    []jsonPoint     `json:"polygon"`
+	PolyLine   []jsonPoint     `json:"polyline"`
+	Text       *jsonText       `json:"text"`
+	Template   string          `json:"template"`
+	Properties []*jsonProperty `json:"properties"`
+}
+
+type jsonText struct {
+	Text       string  `json:"text"`
+	FontFamily *string `json:"fontfamily"`
+	PixelSize  *int    `json:"pixelsize"`
+	Wrap       bool    `json:"wrap"`
+	Color      string  `json:"color"`
+	Bold       bool    `json:"bold"`
+	Italic     bool    `json:"italic"`
+	Underline  bool    `json:"underline"`
+	Strikeout  bool    `json:"strikeout"`
+	Kerning    *bool   `json:"kerning"`
+	HAlign     *string `json:"halign"`
+	VAlign     *string `json:"valign"`
+}
+
+type jsonTemplate struct {
+	Tileset *jsonTileset `json:"tileset"`
+	Object  *jsonObject  `json:"object"`
+}
+
+type jsonPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func readTilesetJSON(r io.Reader, dir string) (*Tileset, error) {
+	log.Debug("readTilesetJSON: reading from io.Reader")
+
+	var jt jsonTileset
+	if err := json.NewDecoder(r).Decode(&jt); err != nil {
+		log.WithError(err).Error("readTilesetJSON: could not decode to Tileset")
+		return nil, err
+	}
+
+	t := jt.toTileset()
+	t.dir = dir
+
+	return validate(*t)
+}
+
+func readTemplateJSON(r io.Reader) (*Template, error) {
+	log.Debug("readTemplateJSON: reading from io.Reader")
+
+	var jt jsonTemplate
+	if err := json.NewDecoder(r).Decode(&jt); err != nil {
+		log.WithError(err).Error("readTemplateJSON: could not decode to Template")
+		return nil, err
+	}
+
+	if jt.Object == nil {
+		log.WithError(ErrInvalidTemplate).Error("readTemplateJSON: template has no object")
+		return nil, ErrInvalidTemplate
+	}
+
+	t := &Template{Object: jt.Object.toObject()}
+	if jt.Tileset != nil {
+		t.Tileset = jt.Tileset.toTileset()
+	}
+
+	return t, nil
+}
+
+func (jm *jsonMap) toMap() (*Map, error) {
+	m := &Map{
+		Version:         jsonString(jm.Version),
+		Orientation:     jm.Orientation,
+		Width:           jm.Width,
+		Height:          jm.Height,
+		TileWidth:       jm.TileWidth,
+		TileHeight:      jm.TileHeight,
+		RenderOrder:     jm.RenderOrder,
+		StaggerAxis:     jm.StaggerAxis,
+		StaggerIndex:    jm.StaggerIndex,
+		HexSideLength:   jm.HexSideLength,
+		BackgroundColor: jm.BackgroundColor,
+		ParallaxOriginX: jm.ParallaxOriginX,
+		ParallaxOriginY: jm.ParallaxOriginY,
+		Infinite:        jm.Infinite,
+		Properties:      toProperties(jm.Properties),
+	}
+
+	for _, jt := range jm.Tilesets {
+		m.Tilesets = append(m.Tilesets, jt.toTileset())
+	}
+
+	// The map is converted as though it were a group, to share the conversion of nested layers.
+	g, err := (&jsonLayer{Layers: jm.Layers}).toGroup()
+	if err != nil {
+		log.WithError(err).Error("jsonMap.toMap: could not convert layers")
+		return nil, err
+	}
+	m.layers = g.layers
+	m.TileLayers, m.ObjectGroups, m.ImageLayers, m.Groups = g.TileLayers, g.ObjectGroups, g.ImageLayers, g.Groups
+
+	return m, nil
+}
+
+func (jt *jsonTileset) toTileset() *Tileset {
+	ts := &Tileset{
+		FirstGID:        jt.FirstGID,
+		Source:          jt.Source,
+		Name:            jt.Name,
+		TileWidth:       jt.TileWidth,
+		TileHeight:      jt.TileHeight,
+		Spacing:         jt.Spacing,
+		Margin:          jt.Margin,
+		Properties:      toProperties(jt.Properties),
+		Tilecount:       jt.TileCount,
+		Columns:         jt.Columns,
+		Image:           toImage(jt.Image, jt.ImageWidth, jt.ImageHeight, jt.TransparentColor),
+		ObjectAlignment: jt.ObjectAlignment,
+	}
+
+	if jt.TileOffset != nil {
+		ts.TileOffset = &TileOffset{X: jt.TileOffset.X, Y: jt.TileOffset.Y}
+	}
+	if jt.Grid != nil {
+		ts.Grid = &Grid{Orientation: jt.Grid.Orientation, Width: jt.Grid.Width, Height: jt.Grid.Height}
+	}
+
+	for _, jtile := range jt.Tiles {
+		t := &Tile{
+			ID:         jtile.ID,
+			Properties: toProperties(jtile.Properties),
+			Image:      toImage(jtile.Image, jtile.ImageWidth, jtile.ImageHeight, ""),
+		}
+		for _, jf := range jtile.Animation {
+			t.Animation = append(t.Animation, &Frame{TileID: jf.TileID, Duration: jf.Duration})
+		}
+		if jtile.ObjectGroup != nil {
+			t.ObjectGroup = jtile.ObjectGroup.toObjectGroup()
+		}
+		ts.Tiles = append(ts.Tiles, t)
+	}
+
+	return ts
+}
+
+func (jl *jsonLayer) toTileLayer() (*TileLayer, error) {
+	l := &TileLayer{
+		Name:       jl.Name,
+		Opacity:    float32(jl.Opacity),
+		OffSetX:    jl.OffSetX,
+		OffSetY:    jl.OffSetY,
+		Visible:    jl.Visible,
+		TintColor:  jl.TintColor,
+		Properties: toProperties(jl.Properties),
+	}
+	l.ParallaxX, l.ParallaxY = jl.parallax()
+
+	data, err := toData(jl.Data, jl.Encoding, jl.Compression)
+	if err != nil {
+		log.WithError(err).WithField("Layer", jl.Name).Error("jsonLayer.toTileLayer: could not convert layer data")
+		return nil, err
+	}
+	l.Data = *data
+
+	for _, jc := range jl.Chunks {
+		cd, err := toData(jc.Data, jl.Encoding, jl.Compression)
+		if err != nil {
+			log.WithError(err).WithField("Layer", jl.Name).Error("jsonLayer.toTileLayer: could not convert chunk data")
+			return nil, err
+		}
+
+		l.Data.Chunks = append(l.Data.Chunks, &Chunk{
+			X:         jc.X,
+			Y:         jc.Y,
+			Width:     jc.Width,
+			Height:    jc.Height,
+			RawData:   cd.RawData,
+			DataTiles: cd.DataTiles,
+		})
+	}
+
+	return l, nil
+}
+
+func (jl *jsonLayer) toGroup() (*Group, error) {
+	g := &Group{
+		Name:       jl.Name,
+		OffSetX:    jl.OffSetX,
+		OffSetY:    jl.OffSetY,
+		Opacity:    float32(jl.Opacity),
+		Visible:    jl.Visible,
+		TintColor:  jl.TintColor,
+		Properties: toProperties(jl.Properties),
+	}
+	g.ParallaxX, g.ParallaxY = jl.parallax()
+
+	for _, cl := range jl.Layers {
+		switch cl.Type {
+		case "tilelayer":
+			l, err := cl.toTileLayer()
+			if err != nil {
+				log.WithError(err).Error("jsonLayer.toGroup: could not convert tile layer")
+				return nil, err
+			}
+			g.layers = append(g.layers, l)
+		case "objectgroup":
+			g.layers = append(g.layers, cl.toObjectGroup())
+		case "imagelayer":
+			g.layers = append(g.layers, cl.toImageLayer())
+		case "group":
+			cg, err := cl.toGroup()
+			if err != nil {
+				log.WithError(err).WithField("Group", cl.Name).Error("jsonLayer.toGroup: could not convert group")
+				return nil, err
+			}
+			g.layers = append(g.layers, cg)
+		default:
+			log.WithField("Type", cl.Type).Debug("jsonLayer.toGroup: skipping unsupported layer type")
+		}
+	}
+	g.TileLayers, g.ObjectGroups, g.ImageLayers, g.Groups = splitLayers(g.layers)
+
+	return g, nil
+}
+
+func (jl *jsonLayer) toObjectGroup() *ObjectGroup {
+	og := &ObjectGroup{
+		Name:       jl.Name,
+		Color:      strings.TrimPrefix(jl.Color, "#"),
+		OffSetX:    jl.OffSetX,
+		OffSetY:    jl.OffSetY,
+		Opacity:    float32(jl.Opacity),
+		Visible:    jl.Visible,
+		TintColor:  jl.TintColor,
+		Properties: toProperties(jl.Properties),
+	}
+	og.ParallaxX, og.ParallaxY = jl.parallax()
+
+	for _, jo := range jl.Objects {
+		og.Objects = append(og.Objects, jo.toObject())
+	}
+
+	return og
+}
+
+func (jl *jsonLayer) toImageLayer() *ImageLayer {
+	il := &ImageLayer{
+		Locked:     jl.Locked,
+		Name:       jl.Name,
+		OffSetX:    jl.OffSetX,
+		OffSetY:    jl.OffSetY,
+		Opacity:    jl.Opacity,
+		Visible:    jl.Visible,
+		TintColor:  jl.TintColor,
+		Properties: toProperties(jl.Properties),
+		Image:      toImage(jl.Image, jl.ImageWidth, jl.ImageHeight, ""),
+	}
+	il.ParallaxX, il.ParallaxY = jl.parallax()
+
+	return il
+}
+
+// parallax returns the parallax factors of the layer, which are 1 when omitted.
+func (jl *jsonLayer) parallax() (x, y float64) {
+	x, y = 1, 1
+	if jl.ParallaxX != nil {
+		x = *jl.ParallaxX
+	}
+	if jl.ParallaxY != nil {
+		y = *jl.ParallaxY
+	}
+	return x, y
+}
+
+func (jo *jsonObject) toObject() *Object {
+	o := &Object{
+		Name:       jo.Name,
+		Type:       jo.Type,
+		X:          jo.X,
+		Y:          jo.Y,
+		Width:      jo.Width,
+		Height:     jo.Height,
+		GID:        jo.GID,
+		ID:         jo.ID,
+		Visible:    jo.Visible == nil || *jo.Visible,
+		Template:   jo.Template,
+		Properties: toProperties(jo.Properties),
+	}
+
+	if jo.Ellipse {
+		o.Ellipse = &struct{}{}
+	}
+	if jo.Point {
+		o.Point = &struct{}{}
+	}
+	if jo.Polygon != nil {
+		o.Polygon = &Polygon{Points: toPointsString(jo.Polygon)}
+	}
+	if jo.PolyLine != nil {
+		o.PolyLine = &PolyLine{Points: toPointsString(jo.PolyLine)}
+	}
+	if jo.Text != nil {
+		o.Text = jo.Text.toText()
+	}
+
+	return o
+}
+
+// toText converts the text of a JSON text object.  Tiled omits the attributes which are the default values, so these
+// are kept from the defaults when not set.
+func (jt *jsonText) toText() *Text {
+	t := defaultText()
+	t.Text = jt.Text
+	t.Wrap = jt.Wrap
+	t.Color = jt.Color
+	t.Bold = jt.Bold
+	t.Italic = jt.Italic
+	t.Underline = jt.Underline
+	t.Strikeout = jt.Strikeout
+
+	if jt.FontFamily != nil {
+		t.FontFamily = *jt.FontFamily
+	}
+	if jt.PixelSize != nil {
+		t.PixelSize = *jt.PixelSize
+	}
+	if jt.Kerning != nil {
+		t.Kerning = *jt.Kerning
+	}
+	if jt.HAlign != nil {
+		t.HAlign = *jt.HAlign
+	}
+	if jt.VAlign != nil {
+		t.VAlign = *jt.VAlign
+	}
+
+	return &t
+}
+
+// toData converts the data of a JSON tile layer or chunk.  CSV data is stored in JSON as an array of GIDs, which are
+// set as DataTiles so that they are decoded as though they were XML.  Base64 data is stored as a string, which is set as
+// the raw data to be decoded as normal.
+func toData(raw json.RawMessage, encoding, compression string) (*Data, error) {
+	d := &Data{}
+	if encoding == "base64" {
+		d.Encoding = encoding
+		d.Compression = compression
+	}
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		// Infinite maps have no layer data, only chunks.
+		return d, nil
+	}
+
+	if d.Encoding == "base64" {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			log.WithError(err).Error("toData: could not decode base64 data string")
+			return nil, err
+		}
+
+		d.RawData = []byte(s)
+		return d, nil
+	}
+
+	var gids []GID
+	if err := json.Unmarshal(raw, &gids); err != nil {
+		log.WithError(err).Error("toData: could not decode data array")
+		return nil, err
+	}
+
+	d.DataTiles = make([]*DataTile, len(gids))
+	for i, gid := range gids {
+		d.DataTiles[i] = &DataTile{GID: gid}
+	}
+
+	return d, nil
+}
+
+func toImage(source string, width, height int, trans string) *Image {
+	if source == "" {
+		return nil
+	}
+
+	return &Image{
+		Source: source,
+		Trans:  strings.TrimPrefix(trans, "#"),
+		Width:  width,
+		Height: height,
+	}
+}
+
+func toProperties(jps []*jsonProperty) []*Property {
+	var ps []*Property
+	for _, jp := range jps {
+		p := &Property{
+			Name:         jp.Name,
+			Type:         jp.Type,
+			PropertyType: jp.PropertyType,
+		}
+
+		if jp.Type == "class" {
+			p.Properties = toClassProperties(jp.Value)
+		} else {
+			p.Value = jsonString(jp.Value)
+		}
+
+		ps = append(ps, p)
+	}
+
+	return ps
+}
+
+// toClassProperties converts the value of a JSON "class" property, which is an object of member names to values.  The
+// types of the members are not stored in the map, so all members are returned untyped.
+func toClassProperties(raw json.RawMessage) []*Property {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		log.WithError(err).Debug("toClassProperties: class value is not an object")
+		return nil
+	}
+
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var ps []*Property
+	for _, name := range names {
+		p := &Property{Name: name}
+		if members[name] != nil && bytes.HasPrefix(bytes.TrimSpace(members[name]), []byte("{")) {
+			p.Type = "class"
+			p.Properties = toClassProperties(members[name])
+		} else {
+			p.Value = jsonString(members[name])
+		}
+		ps = append(ps, p)
+	}
+
+	return ps
+}
+
+// toPointsString converts JSON points to the space separated string of co-ordinates used in TMX.
+func toPointsString(points []jsonPoint) string {
+	strs := make([]string, len(points))
+	for i, p := range points {
+		strs[i] = fmt.Sprintf("%s,%s", strconv.FormatFloat(p.X, 'f', -1, 64), strconv.FormatFloat(p.Y, 'f', -1, 64))
+	}
+
+	return strings.Join(strs, " ")
+}
+
+// jsonString returns the string representation of a JSON value.  Strings are unquoted, all other values (such as
+// numbers and booleans) are returned as they appear in the JSON.
+func jsonString(raw json.RawMessage) string {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	return string(bytes.TrimSpace(raw))
+}
@@ -0,0 +1,135 @@
+package tilepix
+
+import (
+	"encoding/xml"
+
+	"github.com/faiface/pixel"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+  _
+ | |   __ _ _  _ ___ _ _
+ | |__/ _` | || / -_) '_|
+ |____\__,_|\_, \___|_|
+            |__/
+*/
+
+// Layer is implemented by each type of Tiled layer; TileLayer, ObjectGroup, ImageLayer and Group.  This allows layers
+// to be iterated in the order they appear in the map, using `Map.Layers` and `Group.Layers`.
+type Layer interface {
+	// GetName returns the name of the layer.
+	GetName() string
+	// GetProperty returns the layers' Property by its name, or nil if there is no such property.  Properties are
+	// inherited from the groups which contain the layer.
+	GetProperty(name string) *Property
+	// IsVisible returns whether the layer, and all groups which contain it, are visible.
+	IsVisible() bool
+	// Parent returns the group which contains the layer, or nil if it is at the top level of the map.
+	Parent() *Group
+	// TotalOffset returns the offset of the layer, combined with the offsets of the groups which contain it.
+	TotalOffset() pixel.Vec
+	// TotalOpacity returns the opacity of the layer, multiplied by the opacity of the groups which contain it.
+	TotalOpacity() float64
+	// TotalParallax returns the parallax factors of the layer, multiplied by the parallax factors of the groups which
+	// contain it.
+	TotalParallax() pixel.Vec
+	// TotalTint returns the tint colour of the layer, multiplied by the tint colours of the groups which contain it.
+	TotalTint() pixel.RGBA
+}
+
+// layerElement is used to decode any of the TMX layer elements, so that the order of the layers in the document is
+// kept.  Any other elements are skipped.
+type layerElement struct {
+	layer Layer
+}
+
+func (le *layerElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+	case "layer":
+		le.layer = &TileLayer{}
+	case "objectgroup":
+		le.layer = &ObjectGroup{}
+	case "imagelayer":
+		le.layer = &ImageLayer{}
+	case "group":
+		le.layer = &Group{}
+	default:
+		return d.Skip()
+	}
+
+	if err := d.DecodeElement(le.layer, &start); err != nil {
+		log.WithError(err).WithField("Element", start.Name.Local).Error("layerElement.UnmarshalXML: could not decode layer")
+		return err
+	}
+
+	return nil
+}
+
+// layersOf returns the layers decoded from the elements, in order.
+func layersOf(les []*layerElement) []Layer {
+	var ls []Layer
+	for _, le := range les {
+		if le.layer != nil {
+			ls = append(ls, le.layer)
+		}
+	}
+	return ls
+}
+
+// orderLayers returns all of the layers in the slices, sorted by their position in order.  Layers which are not in
+// order, such as those added after the map was read, follow in the order of the slices.  Layers in order which are no
+// longer in any of the slices are dropped.
+func orderLayers(order []Layer, tileLayers []*TileLayer, objectGroups []*ObjectGroup, imageLayers []*ImageLayer, groups []*Group) []Layer {
+	var all []Layer
+	for _, l := range tileLayers {
+		all = append(all, l)
+	}
+	for _, og := range objectGroups {
+		all = append(all, og)
+	}
+	for _, im := range imageLayers {
+		all = append(all, im)
+	}
+	for _, g := range groups {
+		all = append(all, g)
+	}
+
+	present := make(map[Layer]bool, len(all))
+	for _, l := range all {
+		present[l] = true
+	}
+
+	var ls []Layer
+	for _, l := range order {
+		if present[l] {
+			ls = append(ls, l)
+			delete(present, l)
+		}
+	}
+	for _, l := range all {
+		if present[l] {
+			ls = append(ls, l)
+		}
+	}
+
+	return ls
+}
+
+// splitLayers separates the layers by type, keeping their order.
+func splitLayers(ls []Layer) (tileLayers []*TileLayer, objectGroups []*ObjectGroup, imageLayers []*ImageLayer, groups []*Group) {
+	for _, l := range ls {
+		switch l := l.(type) {
+		case *TileLayer:
+			tileLayers = append(tileLayers, l)
+		case *ObjectGroup:
+			objectGroups = append(objectGroups, l)
+		case *ImageLayer:
+			imageLayers = append(imageLayers, l)
+		case *Group:
+			groups = append(groups, l)
+		}
+	}
+	return
+}
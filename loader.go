@@ -0,0 +1,229 @@
+package tilepix
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/faiface/pixel"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+  _                _
+ | |   ___  __ _ __| |___ _ _
+ | |__/ _ \/ _` / _` / -_) '_|
+ |____\___/\__,_\__,_\___|_|
+*/
+
+// Loader reads Tiled maps, sharing the external tilesets and images they use.  Each tileset and image file is only read
+// once, however many maps use it, until it is evicted.  Files are keyed by their path, resolved relative to the file
+// which references them.
+//
+// Each map is given its' own copy of the shared tilesets, so that changes made to a tileset by one map, such as its'
+// first GID, do not affect the others; the pictures of the tileset images are shared.  A Loader is safe for concurrent
+// use.
+type Loader struct {
+	openFileFunc func(name string) (http.File, error)
+
+	mu       sync.Mutex
+	tilesets map[string]*Tileset
+	pictures map[string]pixel.Picture
+	// reading holds a channel for each file being read, which is closed once the file has been read.
+	reading map[string]chan struct{}
+}
+
+// NewLoader returns a Loader which opens files with openFileFunc.  openFileFunc can be nil, in which case os.Open is
+// used.
+func NewLoader(openFileFunc func(name string) (http.File, error)) *Loader {
+	if openFileFunc == nil {
+		openFileFunc = osOpen
+	}
+
+	return &Loader{
+		openFileFunc: openFileFunc,
+		tilesets:     make(map[string]*Tileset),
+		pictures:     make(map[string]pixel.Picture),
+		reading:      make(map[string]chan struct{}),
+	}
+}
+
+// NewFSLoader returns a Loader which opens files from the filesystem, such as an `embed.FS`.
+func NewFSLoader(fsys fs.FS) *Loader {
+	return NewLoader(fsOpen(fsys))
+}
+
+// Read will read, decode and initialise a Tiled Map from a data reader.  The files referenced by the map are relative to
+// dir.
+func (l *Loader) Read(r io.Reader, dir string) (*Map, error) {
+	log.Debug("Loader.Read: reading from io.Reader")
+
+	var m Map
+	if err := xml.NewDecoder(r).Decode(&m); err != nil {
+		log.WithError(err).Error("Loader.Read: could not decode to Map")
+		return nil, err
+	}
+
+	if err := m.initialise(dir, l); err != nil {
+		log.WithError(err).Error("Loader.Read: could not initialise Map")
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// ReadJSON will read, decode and initialise a Tiled Map from a data reader containing a map in the Tiled JSON format.
+// The files referenced by the map are relative to dir.
+func (l *Loader) ReadJSON(r io.Reader, dir string) (*Map, error) {
+	log.Debug("Loader.ReadJSON: reading from io.Reader")
+
+	var jm jsonMap
+	if err := json.NewDecoder(r).Decode(&jm); err != nil {
+		log.WithError(err).Error("Loader.ReadJSON: could not decode to Map")
+		return nil, err
+	}
+
+	m, err := jm.toMap()
+	if err != nil {
+		log.WithError(err).Error("Loader.ReadJSON: could not convert to Map")
+		return nil, err
+	}
+
+	if err := m.initialise(dir, l); err != nil {
+		log.WithError(err).Error("Loader.ReadJSON: could not initialise Map")
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ReadFile will read, decode and initialise a Tiled Map from a file path, opened with the loader.  Files with a `.tmj` or
+// `.json` extension are read as Tiled JSON maps, all others are read as TMX.
+func (l *Loader) ReadFile(filePath string) (*Map, error) {
+	log.WithField("Filepath", filePath).Debug("Loader.ReadFile: reading file")
+
+	f, err := l.openFileFunc(filePath)
+	if err != nil {
+		log.WithError(err).Error("Loader.ReadFile: could not open file")
+		return nil, err
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(filePath)
+
+	if isJSONPath(filePath) {
+		return l.ReadJSON(f, dir)
+	}
+	return l.Read(f, dir)
+}
+
+// Evict will remove the tileset or image at the path from the loader, so it is read again the next time it is used.
+// Maps which have already been read keep using their copy.
+func (l *Loader) Evict(path string) {
+	path = filepath.Clean(path)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.tilesets, path)
+	delete(l.pictures, path)
+}
+
+// Clear will remove all tilesets and images from the loader.
+func (l *Loader) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tilesets = make(map[string]*Tileset)
+	l.pictures = make(map[string]pixel.Picture)
+}
+
+// tileset returns a copy of the external tileset at source, relative to dir.  The tileset is only read the first time it
+// is requested.
+func (l *Loader) tileset(dir, source string) (*Tileset, error) {
+	path := filepath.Clean(filepath.Join(dir, source))
+
+	var ts *Tileset
+	release := l.claim(path, func() (ok bool) {
+		ts, ok = l.tilesets[path]
+		return ok
+	})
+	if release != nil {
+		defer release()
+
+		var err error
+		ts, err = readTilesetSource(l.openFileFunc, dir, source)
+		if err != nil {
+			log.WithError(err).Error("Loader.tileset: could not read tileset source")
+			return nil, err
+		}
+
+		l.mu.Lock()
+		l.tilesets[path] = ts
+		l.mu.Unlock()
+	}
+
+	return ts.clone(), nil
+}
+
+// picture returns the picture of the image at path.  The image is only decoded the first time it is requested.
+func (l *Loader) picture(path string) (pixel.Picture, error) {
+	path = filepath.Clean(path)
+
+	var pic pixel.Picture
+	release := l.claim(path, func() (ok bool) {
+		pic, ok = l.pictures[path]
+		return ok
+	})
+	if release == nil {
+		return pic, nil
+	}
+	defer release()
+
+	pic, err := loadPictureFromFile(l.openFileFunc, path)
+	if err != nil {
+		log.WithError(err).Error("Loader.picture: could not load picture")
+		return nil, err
+	}
+
+	l.mu.Lock()
+	l.pictures[path] = pic
+	l.mu.Unlock()
+
+	return pic, nil
+}
+
+// claim waits until the file at path has been cached, or is not being read by another goroutine.  cached is called with
+// the lock held, and reports whether the file has been cached.  If it has not, claim returns a function which the caller
+// must call once it has read and cached the file, or failed to; until then, other requests for the file wait for it
+// rather than reading the file again.  If the file has been cached, claim returns nil.
+func (l *Loader) claim(path string, cached func() bool) (release func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for !cached() {
+		wait, ok := l.reading[path]
+		if !ok {
+			done := make(chan struct{})
+			l.reading[path] = done
+
+			return func() {
+				l.mu.Lock()
+				delete(l.reading, path)
+				l.mu.Unlock()
+				close(done)
+			}
+		}
+
+		l.mu.Unlock()
+		<-wait
+		l.mu.Lock()
+	}
+
+	return nil
+}
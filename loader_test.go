@@ -0,0 +1,154 @@
+package tilepix
+
+import (
+	_ "image/png"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingOpen returns a function which opens files with os.Open, and counts the number of times each is opened.
+func countingOpen() (func(name string) (http.File, error), func(name string) int) {
+	var mu sync.Mutex
+	counts := make(map[string]int)
+
+	open := func(name string) (http.File, error) {
+		mu.Lock()
+		counts[filepath.Clean(name)]++
+		mu.Unlock()
+		return osOpen(name)
+	}
+	count := func(name string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return counts[name]
+	}
+	return open, count
+}
+
+func TestLoader_ReadFile(t *testing.T) {
+	open, count := countingOpen()
+	l := NewLoader(open)
+
+	m1, err := l.ReadFile("testdata/external_tileset.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2, err := l.ReadFile("testdata/external_tileset.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := count("testdata/tileset.tsx"); got != 1 {
+		t.Errorf("Expected tileset to be read once, got %d", got)
+	}
+	if got := count("testdata/tileset.png"); got != 1 {
+		t.Errorf("Expected tileset image to be read once, got %d", got)
+	}
+
+	// Each map has its' own tileset, sharing the picture.
+	ts1, ts2 := m1.Tilesets[0], m2.Tilesets[0]
+	if ts1 == ts2 {
+		t.Error("Expected each map to have its' own tileset")
+	}
+	if ts1.setSprite() != ts2.setSprite() {
+		t.Error("Expected maps to share the tileset picture")
+	}
+	if ts1.parentMap != m1 || ts2.parentMap != m2 {
+		t.Error("Expected each tileset to belong to its' own map")
+	}
+
+	l.Evict("testdata/tileset.tsx")
+	l.Evict("./testdata/tileset.png")
+
+	m3, err := l.ReadFile("testdata/external_tileset.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := count("testdata/tileset.tsx"); got != 2 {
+		t.Errorf("Expected evicted tileset to be read again, got %d reads", got)
+	}
+	if m3.Tilesets[0].setSprite() == ts1.setSprite() {
+		t.Error("Expected evicted picture to be loaded again")
+	}
+
+	l.Clear()
+
+	if _, err := l.ReadFile("testdata/external_tileset.tmx"); err != nil {
+		t.Fatal(err)
+	}
+	if got := count("testdata/tileset.png"); got != 3 {
+		t.Errorf("Expected cleared picture to be loaded again, got %d reads", got)
+	}
+}
+
+func TestTileset_clone(t *testing.T) {
+	ts, err := readTilesetSource(osOpen, "testdata", "tileset.tsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Properties = []*Property{{Name: "p", Properties: []*Property{{Name: "member"}}}}
+	ts.Tiles = []*Tile{{
+		ID:          1,
+		Properties:  []*Property{{Name: "tile"}},
+		ObjectGroup: &ObjectGroup{Objects: []*Object{{Name: "object", Polygon: &Polygon{Points: "0,0 1,1"}}}},
+		Animation:   []*Frame{{TileID: 1, Duration: 100}},
+	}}
+
+	c := ts.clone()
+	c.FirstGID = 10
+	c.Image.Source = "changed.png"
+	c.Properties[0].Properties[0].Value = "changed"
+	c.Tiles[0].Properties[0].Value = "changed"
+	c.Tiles[0].ObjectGroup.Objects[0].Name = "changed"
+	c.Tiles[0].ObjectGroup.Objects[0].Polygon.Points = "changed"
+	c.Tiles[0].Animation[0].Duration = 200
+
+	if ts.FirstGID == 10 || ts.Image.Source == "changed.png" {
+		t.Errorf("Changing the clone changed the tileset, got %v", ts)
+	}
+	if ts.Properties[0].Properties[0].Value != "" || ts.Tiles[0].Properties[0].Value != "" {
+		t.Error("Changing the clones' properties changed the tileset")
+	}
+	if o := ts.Tiles[0].ObjectGroup.Objects[0]; o.Name != "object" || o.Polygon.Points != "0,0 1,1" {
+		t.Errorf("Changing the clones' tile objects changed the tileset, got %v", o)
+	}
+	if f := ts.Tiles[0].Animation[0]; f.Duration != 100 {
+		t.Errorf("Changing the clones' animation changed the tileset, got %v", f)
+	}
+}
+
+func TestLoader_ReadFile_concurrent(t *testing.T) {
+	open, count := countingOpen()
+	// Reading slowly gives every goroutine the chance to request the files while they are being read.
+	l := NewLoader(func(name string) (http.File, error) {
+		time.Sleep(20 * time.Millisecond)
+		return open(name)
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := l.ReadFile("testdata/external_tileset.tmx"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if got := count("testdata/tileset.tsx"); got != 1 {
+		t.Errorf("Expected tileset to be read once, got %d", got)
+	}
+	if got := count("testdata/tileset.png"); got != 1 {
+		t.Errorf("Expected tileset image to be read once, got %d", got)
+	}
+}
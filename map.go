@@ -1,11 +1,15 @@
 package tilepix
 
 import (
+	"encoding/xml"
 	"fmt"
+	"image"
 	"image/color"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/faiface/pixel"
-	"github.com/faiface/pixel/pixelgl"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -25,58 +29,168 @@ type Map struct {
 	// Width is the number of tiles - not the width in pixels
 	Width int `xml:"width,attr"`
 	// Height is the number of tiles - not the height in pixels
-	Height       int            `xml:"height,attr"`
-	TileWidth    int            `xml:"tilewidth,attr"`
-	TileHeight   int            `xml:"tileheight,attr"`
-	Properties   []*Property    `xml:"properties>property"`
-	Tilesets     []*Tileset     `xml:"tileset"`
-	TileLayers   []*TileLayer   `xml:"layer"`
-	ObjectGroups []*ObjectGroup `xml:"objectgroup"`
-	Infinite     bool           `xml:"infinite,attr"`
-	ImageLayers  []*ImageLayer  `xml:"imagelayer"`
-
-	canvas *pixelgl.Canvas
+	Height     int `xml:"height,attr"`
+	TileWidth  int `xml:"tilewidth,attr"`
+	TileHeight int `xml:"tileheight,attr"`
+	// RenderOrder is the order tiles in tile layers are drawn; one of "right-down", "right-up", "left-down" or
+	// "left-up".  Tiled only uses this for orthogonal maps.  Defaults to "right-down".
+	RenderOrder string `xml:"renderorder,attr"`
+	// StaggerAxis is the axis, "x" or "y", along which every other row or column is shifted in staggered and
+	// hexagonal maps.
+	StaggerAxis string `xml:"staggeraxis,attr"`
+	// StaggerIndex is whether the "odd" or "even" rows or columns are shifted in staggered and hexagonal maps.
+	StaggerIndex string `xml:"staggerindex,attr"`
+	// HexSideLength is the length, in pixels, of the flat side of the tiles in hexagonal maps.
+	HexSideLength int `xml:"hexsidelength,attr"`
+	// BackgroundColor is the Tiled colour string of the maps' background, empty if it has none.  Use
+	// GetBackgroundColor to decode it.
+	BackgroundColor string `xml:"backgroundcolor,attr"`
+	// ParallaxOriginX and ParallaxOriginY are the position, in Tiled pixel co-ordinates, at which layers with parallax
+	// factors are drawn in their place in the map.
+	ParallaxOriginX float64        `xml:"parallaxoriginx,attr"`
+	ParallaxOriginY float64        `xml:"parallaxoriginy,attr"`
+	Properties      []*Property    `xml:"properties>property"`
+	Tilesets        []*Tileset     `xml:"tileset"`
+	TileLayers      []*TileLayer   `xml:"-"`
+	ObjectGroups    []*ObjectGroup `xml:"-"`
+	Infinite        bool           `xml:"infinite,attr"`
+	ImageLayers     []*ImageLayer  `xml:"-"`
+	// Groups holds the group layers at the top level of the map.  The layers within groups are not included in the
+	// maps' TileLayers, ObjectGroups or ImageLayers.
+	Groups []*Group `xml:"-"`
+	// StartX and StartY are the tile co-ordinates of the top-left of the map.  These are only set for infinite maps,
+	// where they, and the maps' Width and Height, are calculated from the area covered by all layer chunks.
+	StartX int `xml:"-"`
+	StartY int `xml:"-"`
+
+	// layers holds the top level layers in the order they appear in the map.
+	layers []Layer
+
+	// renderer creates the canvases the map is drawn to.  If nil, the registered renderer is used.
+	renderer Renderer
+	// canvases are the canvases the map was last drawn to, which each cover part of the area drawn.
+	canvases []Canvas
+	// elapsed is the total time passed to `Map.Update`, used to play tile animations.
+	elapsed time.Duration
 	// dir is the directory the tmx file is located in.  This is used to access images for tilesets via a relative path.
 	dir string
+	// loader is used to open all of the files the map references, such as tilesets and images.
+	loader *Loader
 }
 
-// DrawAll will draw all tile layers and image layers to the target.
+// DrawAll will draw all visible tile layers and image layers, including those in groups, to the target.  Layers are
+// drawn in the order they appear in the map, so later layers are drawn on top.
 // Tile layers are first draw to their own `pixel.Batch`s for efficiency.
-// All layers are drawn to a `pixel.Canvas` before being drawn to the target.
+// All layers are drawn to canvases, created by the maps' renderer, before being drawn to the target; see `Renderer`.  The
+// map is split between several canvases if it is larger than the maximum size of a canvas, so canvases do not exceed
+// the texture size limits of graphics cards.
+// ErrNoRenderer is returned if the map has no renderer and none has been registered.
+// Layers are tinted by their tint colour.  Layers with parallax factors are drawn in place, as if the camera were at the
+// maps' parallax origin; use `Map.DrawAllWithCamera` to move them relative to a camera.
 //
 // - target - The target to draw layers to.
-// - clearColour - The colour to clear the maps' canvas before drawing.
+// - clearColour - The colour to clear the maps' canvas before drawing.  If nil, the maps' background colour is used.
 // - mat - The matrix to draw the canvas to the target with.
 func (m *Map) DrawAll(target pixel.Target, clearColour color.Color, mat pixel.Matrix) error {
-	if m.canvas == nil {
-		m.canvas = pixelgl.NewCanvas(m.Bounds())
+	if err := m.draw(target, clearColour, mat, m.parallaxOrigin(), nil); err != nil {
+		log.WithError(err).Error("Map.DrawAll: could not draw map")
+		return err
 	}
-	m.canvas.Clear(clearColour)
+	return nil
+}
 
-	for _, l := range m.TileLayers {
-		if err := l.Draw(m.canvas); err != nil {
-			log.WithError(err).Error("Map.DrawAll: could not draw layer")
+// DrawAllWithCamera will draw the map in the same way as `Map.DrawAll`, with layers which have parallax factors moved
+// relative to the camera; see `Map.ParallaxOffset`.
+//
+// - camera - The position, in game co-ordinates, of the centre of the view of the map.
+func (m *Map) DrawAllWithCamera(target pixel.Target, clearColour color.Color, mat pixel.Matrix, camera pixel.Vec) error {
+	if err := m.draw(target, clearColour, mat, camera, nil); err != nil {
+		log.WithError(err).Error("Map.DrawAllWithCamera: could not draw map")
+		return err
+	}
+	return nil
+}
+
+// DrawRect will draw the visible layers of the map in the same way as `Map.DrawAllWithCamera`, but only the parts of
+// the map within view, in game co-ordinates, are drawn.  Only the chunks of tile layers and the image layers which
+// intersect view are drawn, to canvases covering view, so drawing a small part of a large map is cheap.  The map is drawn
+// to the target at the same position as `Map.DrawAll` would draw it.
+func (m *Map) DrawRect(target pixel.Target, clearColour color.Color, mat pixel.Matrix, camera pixel.Vec, view pixel.Rect) error {
+	if err := m.draw(target, clearColour, mat, camera, &view); err != nil {
+		log.WithError(err).Error("Map.DrawRect: could not draw map")
+		return err
+	}
+	return nil
+}
+
+// draw will draw the map to the target, only drawing the parts within view if it is not nil.
+func (m *Map) draw(target pixel.Target, clearColour color.Color, mat pixel.Matrix, camera pixel.Vec, view *pixel.Rect) error {
+	if clearColour == nil {
+		bg, err := m.GetBackgroundColor()
+		if err != nil {
+			log.WithError(err).Error("Map.draw: could not get background colour")
 			return err
 		}
+		clearColour = bg
+	}
+
+	area := m.Bounds()
+	if view != nil {
+		area = *view
+	}
+	rects := splitRect(area, maxCanvasSize)
+
+	if len(m.canvases) > len(rects) {
+		m.canvases = m.canvases[:len(rects)]
 	}
+	for i, rect := range rects {
+		if i == len(m.canvases) {
+			r := m.getRenderer()
+			if r == nil {
+				log.WithError(ErrNoRenderer).Error("Map.draw: no renderer to create the canvas with")
+				return ErrNoRenderer
+			}
+			m.canvases = append(m.canvases, r.NewCanvas(rect))
+		} else if m.canvases[i].Bounds() != rect {
+			m.canvases[i].SetBounds(rect)
+		}
+
+		canvas := m.canvases[i]
+		canvas.Clear(clearColour)
 
-	for _, il := range m.ImageLayers {
-		// The matrix shift is because images are drawn from the top-left in Tiled.
-		if err := il.Draw(m.canvas, pixel.IM.Moved(pixel.V(0, m.pixelHeight()))); err != nil {
-			log.WithError(err).Error("Map.DrawAll: could not draw image layer")
+		if err := m.drawLayers(canvas, m.Layers(), camera, rect); err != nil {
+			log.WithError(err).Error("Map.draw: could not draw layers")
 			return err
 		}
-	}
 
-	m.canvas.Draw(target, mat.Moved(m.Bounds().Center()))
+		canvas.Draw(target, mat.Moved(rect.Center()))
+	}
 
 	return nil
 }
 
+// SetRenderer sets the renderer the map is drawn with by `Map.DrawAll`, in place of the registered renderer.  Setting a
+// nil renderer reverts to the registered renderer.
+func (m *Map) SetRenderer(r Renderer) {
+	m.renderer = r
+	// The canvases are created again by the new renderer.
+	m.canvases = nil
+}
+
+// Update will advance the maps' tile animations by dt; this should be called once per frame before drawing.  Only the
+// tile layers with animated tiles which have changed frame are marked dirty, so other layers are not re-batched.
+func (m *Map) Update(dt time.Duration) {
+	m.elapsed += dt
+
+	for _, l := range m.allTileLayers() {
+		l.updateAnimations()
+	}
+}
+
 // GenerateTileObjectLayer will create an object layer which contains all objects as defined by individual tiles.
 func (m *Map) GenerateTileObjectLayer() error {
 	for _, ts := range m.Tilesets {
-		objGroup := ts.GenerateTileObjectLayer(m.TileLayers)
+		objGroup := ts.GenerateTileObjectLayer(m.allTileLayers())
 		if err := objGroup.decode(); err != nil {
 			log.WithField("ObjectGroup", objGroup).WithError(err).Error("Map.GenerateTileObjectLayer: could not deccode object group")
 			return err
@@ -87,33 +201,77 @@ func (m *Map) GenerateTileObjectLayer() error {
 	return nil
 }
 
-// GetImageLayerByName returns a Map's ImageLayer by its name
+// Layers returns the top level layers of the map, in the order they appear in the map.  The layers within groups are
+// available from `Group.Layers`.
+func (m *Map) Layers() []Layer {
+	return orderLayers(m.layers, m.TileLayers, m.ObjectGroups, m.ImageLayers, m.Groups)
+}
+
+// GetGroupByName returns a Map's Group by its name, or by its path through the groups which contain it, such as
+// "Level/Enemies".
+func (m *Map) GetGroupByName(name string) *Group {
+	for _, g := range m.allGroups() {
+		if g.Name == name {
+			return g
+		}
+	}
+	return findGroup(m.Groups, name)
+}
+
+// GetImageLayerByName returns a Map's ImageLayer by its name.  Layers nested in groups are also found, either by their
+// name or their path, such as "Level/Background".
 func (m *Map) GetImageLayerByName(name string) *ImageLayer {
-	for _, l := range m.ImageLayers {
+	for _, l := range m.allImageLayers() {
 		if l.Name == name {
 			return l
 		}
 	}
+
+	if g, name := m.splitLayerPath(name); g != nil {
+		for _, l := range g.ImageLayers {
+			if l.Name == name {
+				return l
+			}
+		}
+	}
 	return nil
 }
 
-// GetObjectLayerByName returns a Map's ObjectGroup by its name
+// GetObjectLayerByName returns a Map's ObjectGroup by its name.  Layers nested in groups are also found, either by their
+// name or their path, such as "Level/Enemies".
 func (m *Map) GetObjectLayerByName(name string) *ObjectGroup {
-	for _, l := range m.ObjectGroups {
+	for _, l := range m.allObjectGroups() {
 		if l.Name == name {
 			return l
 		}
 	}
+
+	if g, name := m.splitLayerPath(name); g != nil {
+		for _, l := range g.ObjectGroups {
+			if l.Name == name {
+				return l
+			}
+		}
+	}
 	return nil
 }
 
-// GetTileLayerByName returns a Map's TileLayer by its name
+// GetTileLayerByName returns a Map's TileLayer by its name.  Layers nested in groups are also found, either by their
+// name or their path, such as "Level/Terrain".
 func (m *Map) GetTileLayerByName(name string) *TileLayer {
-	for _, l := range m.TileLayers {
+	for _, l := range m.allTileLayers() {
 		if l.Name == name {
 			return l
 		}
 	}
+
+	if g, name := m.splitLayerPath(name); g != nil {
+		for _, l := range g.TileLayers {
+			if l.Name == name {
+				return l
+			}
+		}
+	}
 	return nil
 }
 
@@ -121,12 +279,137 @@ func (m *Map) GetTileLayerByName(name string) *TileLayer {
 func (m *Map) GetObjectByName(name string) []*Object {
 	var objs []*Object
 
-	for _, og := range m.ObjectGroups {
+	for _, og := range m.allObjectGroups() {
 		objs = append(objs, og.GetObjectByName(name)...)
 	}
 	return objs
 }
 
+// GetObjectByID returns the Maps' Object with the given ID, or nil if there is no such object.
+func (m *Map) GetObjectByID(id ID) *Object {
+	for _, og := range m.allObjectGroups() {
+		if o := og.GetObjectByID(id); o != nil {
+			return o
+		}
+	}
+	return nil
+}
+
+// GetBackgroundColor returns the background colour of the map.  This is transparent when the map has no background
+// colour.
+func (m *Map) GetBackgroundColor() (color.RGBA, error) {
+	if m.BackgroundColor == "" {
+		return color.RGBA{}, nil
+	}
+	return parseColor(m.BackgroundColor)
+}
+
+// GetProperty returns the Maps' Property by its name, or nil if there is no such property.
+func (m *Map) GetProperty(name string) *Property {
+	return getProperty(m.Properties, name)
+}
+
+// Neighbours returns the co-ordinates of the tiles which share an edge with the tile, respecting the maps' orientation.
+// Tiles in hexagonal maps have six neighbours, all others have four; in staggered and hexagonal maps these depend on
+// whether the tiles' row or column is shifted.  Neighbours outside of the map are included.
+func (m *Map) Neighbours(tile pixel.Vec) []pixel.Vec {
+	x, y := int(math.Floor(tile.X)), int(math.Floor(tile.Y))
+
+	var ns [][2]int
+	if m.isStaggered() {
+		ns = m.staggerParams().neighbours(x, y)
+	} else {
+		ns = [][2]int{{x, y - 1}, {x - 1, y}, {x + 1, y}, {x, y + 1}}
+	}
+
+	vs := make([]pixel.Vec, len(ns))
+	for i, n := range ns {
+		vs[i] = pixel.V(float64(n[0]), float64(n[1]))
+	}
+	return vs
+}
+
+// TileToWorld will convert Tiled tile co-ordinates to a game position, respecting the maps' orientation.  Tile
+// co-ordinates are measured from the top-left of the map, as in Tiled, and may be fractional; the tile (x, y) covers
+// the co-ordinates from (x, y) to (x+1, y+1).  For infinite maps, tile co-ordinates are relative to the Tiled origin and
+// may be negative.
+//
+// For orthogonal maps the top-left corner of the tile is returned, for isometric maps this is the top corner of the
+// tiles' diamond.  For staggered and hexagonal maps this is the top-left corner of the tiles' bounding box, and the
+// fractional part of the co-ordinates is a position within the bounding box.
+func (m *Map) TileToWorld(tile pixel.Vec) pixel.Vec {
+	tw, th := float64(m.TileWidth), float64(m.TileHeight)
+
+	if m.isStaggered() {
+		p := m.staggerParams()
+		x, y := math.Floor(tile.X), math.Floor(tile.Y)
+		pos := p.tileToPixel(int(x), int(y)).Add(pixel.V((tile.X-x)*p.tileWidth, (tile.Y-y)*p.tileHeight))
+		return pixel.V(pos.X, m.originY()-pos.Y)
+	}
+
+	if m.isIsometric() {
+		return pixel.V(
+			m.originX()+(tile.X-tile.Y)*tw/2,
+			m.originY()-(tile.X+tile.Y)*th/2,
+		)
+	}
+
+	return pixel.V(tile.X*tw, m.originY()-tile.Y*th)
+}
+
+// WorldToTile will convert a game position to Tiled tile co-ordinates, respecting the maps' orientation.  This is the
+// inverse of `Map.TileToWorld`; the tile containing the position can be found by flooring the components of the result.
+func (m *Map) WorldToTile(world pixel.Vec) pixel.Vec {
+	tw, th := float64(m.TileWidth), float64(m.TileHeight)
+
+	if m.isStaggered() {
+		p := m.staggerParams()
+		pos := pixel.V(world.X, m.originY()-world.Y)
+		x, y := p.pixelToTile(pos)
+		rel := pos.Sub(p.tileToPixel(x, y))
+		return pixel.V(float64(x)+rel.X/p.tileWidth, float64(y)+rel.Y/p.tileHeight)
+	}
+
+	if m.isIsometric() {
+		diffXY := (world.X - m.originX()) / (tw / 2)
+		sumXY := (m.originY() - world.Y) / (th / 2)
+		return pixel.V((sumXY+diffXY)/2, (sumXY-diffXY)/2)
+	}
+
+	return pixel.V(world.X/tw, (m.originY()-world.Y)/th)
+}
+
+// ParallaxOffset returns the offset, in game co-ordinates, to draw the layer with for its' parallax factors, when the
+// centre of the view is at the camera position.  As in Tiled, layers are in their place in the map when the camera is
+// at the maps' parallax origin; a parallax factor of 1 keeps the layer in place, and a factor of 0 moves the layer with
+// the camera.
+func (m *Map) ParallaxOffset(l Layer, camera pixel.Vec) pixel.Vec {
+	f := l.TotalParallax()
+	return camera.Sub(m.parallaxOrigin()).ScaledXY(pixel.V(1-f.X, 1-f.Y))
+}
+
+// parallaxOrigin returns the maps' parallax origin in game co-ordinates.
+func (m *Map) parallaxOrigin() pixel.Vec {
+	return pixel.V(m.ParallaxOriginX, m.originY()-m.ParallaxOriginY)
+}
+
+// UnmarshalXML decodes a map.  The layers are decoded in the order they appear, which is kept for `Map.Layers`.
+func (m *Map) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type tiledMap Map
+	raw := struct {
+		*tiledMap
+		Layers []*layerElement `xml:",any"`
+	}{tiledMap: (*tiledMap)(m)}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		log.WithError(err).Error("Map.UnmarshalXML: could not decode map")
+		return err
+	}
+
+	m.layers = layersOf(raw.Layers)
+	m.TileLayers, m.ObjectGroups, m.ImageLayers, m.Groups = splitLayers(m.layers)
+	return nil
+}
+
 func (m *Map) String() string {
 	return fmt.Sprintf(
 		"Map{Version: %s, Tile dimensions: %dx%d, Properties: %v, Tilesets: %v, TileLayers: %v, Object layers: %v, Image layers: %v}",
@@ -141,9 +424,26 @@ func (m *Map) String() string {
 	)
 }
 
-// Bounds will return a pixel rectangle representing the width-height in pixels.
+// Bounds will return a pixel rectangle representing the width-height in pixels.  For infinite maps the rectangle is
+// positioned relative to the Tiled origin, and so may have negative co-ordinates.  For isometric maps the rectangle
+// contains the diamond shape of the map.
 func (m *Map) Bounds() pixel.Rect {
-	return pixel.R(0, 0, m.pixelWidth(), m.pixelHeight())
+	if m.isStaggered() {
+		p := m.staggerParams()
+		topLeft := p.gridToPixel(m.StartX, m.StartY)
+		size := p.size(m.Width, m.Height)
+		return pixel.R(topLeft.X, m.originY()-topLeft.Y-size.Y, topLeft.X+size.X, m.originY()-topLeft.Y)
+	}
+
+	minX, maxX := float64(m.StartX), float64(m.StartX+m.Width)
+	minY, maxY := float64(m.StartY), float64(m.StartY+m.Height)
+
+	return pixel.R(
+		m.TileToWorld(pixel.V(minX, maxY)).X,
+		m.TileToWorld(pixel.V(maxX, maxY)).Y,
+		m.TileToWorld(pixel.V(maxX, minY)).X,
+		m.TileToWorld(pixel.V(minX, minY)).Y,
+	)
 }
 
 // Centre will return a pixel vector reprensenting the center of the bounds.
@@ -151,13 +451,346 @@ func (m *Map) Centre() pixel.Vec {
 	return m.Bounds().Center()
 }
 
-func (m *Map) pixelWidth() float64 {
-	return float64(m.Width * m.TileWidth)
+// allTileLayers returns the tile layers of the map, including those nested in groups.
+func (m *Map) allTileLayers() []*TileLayer {
+	ls := append([]*TileLayer(nil), m.TileLayers...)
+	for _, g := range m.Groups {
+		ls = append(ls, g.allTileLayers()...)
+	}
+	return ls
+}
+
+// allObjectGroups returns the object groups of the map, including those nested in groups.
+func (m *Map) allObjectGroups() []*ObjectGroup {
+	ogs := append([]*ObjectGroup(nil), m.ObjectGroups...)
+	for _, g := range m.Groups {
+		ogs = append(ogs, g.allObjectGroups()...)
+	}
+	return ogs
+}
+
+// allImageLayers returns the image layers of the map, including those nested in groups.
+func (m *Map) allImageLayers() []*ImageLayer {
+	ils := append([]*ImageLayer(nil), m.ImageLayers...)
+	for _, g := range m.Groups {
+		ils = append(ils, g.allImageLayers()...)
+	}
+	return ils
+}
+
+// allGroups returns the groups of the map, including those nested in other groups.
+func (m *Map) allGroups() []*Group {
+	var gs []*Group
+	groups := m.Groups
+	for len(groups) > 0 {
+		gs = append(gs, groups...)
+
+		var nested []*Group
+		for _, g := range groups {
+			nested = append(nested, g.Groups...)
+		}
+		groups = nested
+	}
+	return gs
+}
+
+// splitLayerPath will split a layer path, such as "Level/Enemies", into the group containing the layer and the name of
+// the layer.  A nil group is returned if the path has no group, or the group cannot be found.
+func (m *Map) splitLayerPath(path string) (*Group, string) {
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return nil, path
+	}
+
+	return findGroup(m.Groups, path[:i]), path[i+1:]
+}
+
+// drawOrder returns the indices of the tiles in a layers' DecodedTiles, in the order they should be drawn.  Tiles are
+// drawn row by row from the top of the map, which draws isometric maps from back to front.  In maps where the columns
+// are shifted, the shifted columns in each row are lower, so are drawn after the rest of the row.
+//
+// As in Tiled, the maps' RenderOrder is only used for orthogonal maps.
+func (m *Map) drawOrder() []int {
+	return m.drawOrderWithin(image.Rect(0, 0, m.Width, m.Height))
+}
+
+// drawOrderWithin returns the indices of the tiles in a layers' DecodedTiles within the cells, as columns and rows of
+// the layer, in the order they should be drawn; see `Map.drawOrder`.
+func (m *Map) drawOrderWithin(cells image.Rectangle) []int {
+	order := make([]int, 0, cells.Dx()*cells.Dy())
+
+	if m.isStaggered() && m.StaggerAxis == "x" {
+		p := m.staggerParams()
+		for y := cells.Min.Y; y < cells.Max.Y; y++ {
+			for _, shifted := range []bool{false, true} {
+				for x := cells.Min.X; x < cells.Max.X; x++ {
+					if p.doStagger(x+m.StartX, y+m.StartY) == shifted {
+						order = append(order, y*m.Width+x)
+					}
+				}
+			}
+		}
+		return order
+	}
+
+	upward, leftward := m.renderDirection()
+	for row := cells.Min.Y; row < cells.Max.Y; row++ {
+		y := row
+		if upward {
+			y = cells.Max.Y - (row - cells.Min.Y) - 1
+		}
+		for col := cells.Min.X; col < cells.Max.X; col++ {
+			x := col
+			if leftward {
+				x = cells.Max.X - (col - cells.Min.X) - 1
+			}
+			order = append(order, y*m.Width+x)
+		}
+	}
+	return order
+}
+
+// renderDirection returns whether the rows of tiles are drawn from the bottom of the map upwards, and whether the tiles
+// in each row are drawn from the right leftwards.  As in Tiled, the maps' RenderOrder is only used for orthogonal maps.
+func (m *Map) renderDirection() (upward, leftward bool) {
+	if m.isIsometric() || m.isStaggered() {
+		return false, false
+	}
+	return strings.HasSuffix(m.RenderOrder, "-up"), strings.HasPrefix(m.RenderOrder, "left-")
+}
+
+// cellsWithin returns the cells, as columns and rows of a layers' DecodedTiles, which may hold tiles drawn within rect.
+// rect is relative to the layer, without the layers' offset.  The cells returned may hold tiles outside rect, but hold
+// all of the tiles within it.
+func (m *Map) cellsWithin(rect pixel.Rect) image.Rectangle {
+	// Tiles may be larger than the cells of the map, and are shifted by the tile offset of their tileset, so the
+	// rectangle is grown to include the cells of tiles which overlap it.
+	margin := pixel.V(float64(m.TileWidth), float64(m.TileHeight))
+	for _, ts := range m.Tilesets {
+		offset := ts.drawOffset()
+		margin.X = math.Max(margin.X, float64(ts.TileWidth)+math.Abs(offset.X))
+		margin.Y = math.Max(margin.Y, float64(ts.TileHeight)+math.Abs(offset.Y))
+	}
+	rect = pixel.R(rect.Min.X-margin.X, rect.Min.Y-margin.Y, rect.Max.X+margin.X, rect.Max.Y+margin.Y)
+
+	var cells image.Rectangle
+	addCell := func(tile pixel.Vec) {
+		x, y := int(math.Floor(tile.X)), int(math.Floor(tile.Y))
+		cells = cells.Union(image.Rect(x, y, x+1, y+1))
+	}
+
+	if m.isIsometric() || m.isStaggered() {
+		for _, corner := range []pixel.Vec{rect.Min, rect.Max, pixel.V(rect.Min.X, rect.Max.Y), pixel.V(rect.Max.X, rect.Min.Y)} {
+			addCell(m.WorldToTile(corner).Sub(pixel.V(float64(m.StartX), float64(m.StartY))))
+		}
+		return cells.Intersect(image.Rect(0, 0, m.Width, m.Height))
+	}
+
+	// The tiles of orthogonal maps are laid out on a grid of the size of the tiles of their tileset, or the cells of the
+	// map for tilesets which are a collection of images; see `DecodedTile.Position`.
+	grids := []pixel.Vec{pixel.V(float64(m.TileWidth), float64(m.TileHeight))}
+	for _, ts := range m.Tilesets {
+		if !ts.IsCollection() {
+			grids = append(grids, pixel.V(float64(ts.TileWidth), float64(ts.TileHeight)))
+		}
+	}
+
+	origin := m.tileOrigin()
+	for _, grid := range grids {
+		for _, corner := range []pixel.Vec{rect.Min, rect.Max} {
+			addCell(pixel.V(corner.X/grid.X-origin.X, float64(m.Height)+origin.Y-corner.Y/grid.Y))
+		}
+	}
+	return cells.Intersect(image.Rect(0, 0, m.Width, m.Height))
+}
+
+// isIsometric returns whether the map uses an isometric, diamond shaped, grid.
+func (m *Map) isIsometric() bool {
+	return m.Orientation == "isometric"
 }
+
+// isStaggered returns whether the map uses a staggered or hexagonal grid, where every other row or column is shifted.
+func (m *Map) isStaggered() bool {
+	return m.Orientation == "staggered" || m.Orientation == "hexagonal"
+}
+
 func (m *Map) pixelHeight() float64 {
+	if m.isStaggered() {
+		return m.staggerParams().size(m.Width, m.Height).Y
+	}
+	if m.isIsometric() {
+		return float64((m.Width+m.Height)*m.TileHeight) / 2
+	}
 	return float64(m.Height * m.TileHeight)
 }
 
+// originX returns the game X co-ordinate of the Tiled origin.  For isometric maps the left corner of the map is at zero,
+// so the origin, which is the top corner, is moved across by the height of the map.
+func (m *Map) originX() float64 {
+	if m.isIsometric() && !m.Infinite {
+		return float64(m.Height*m.TileWidth) / 2
+	}
+	return 0
+}
+
+// originY returns the game Y co-ordinate of the Tiled origin.  Tiled calculates co-ordinates from the top-left, so Y
+// co-ordinates are flipped about this point.  For finite maps this is the top of the map; infinite maps keep the origin
+// in place, as they have no fixed top.
+func (m *Map) originY() float64 {
+	if m.Infinite {
+		return 0
+	}
+	return m.pixelHeight()
+}
+
+// tileOrigin returns the offset, in tiles, to apply to the position of a tile in a layers' DecodedTiles.
+func (m *Map) tileOrigin() pixel.Vec {
+	if m.Infinite {
+		return pixel.V(float64(m.StartX), float64(-m.StartY-m.Height))
+	}
+	return pixel.ZV
+}
+
+// cellBounds returns the area, in game co-ordinates, of the cell at the index of a layers' DecodedTiles.  This is
+// relative to the layer, without the layers' offset.  The area is only the cell for orthogonal maps; the cells of other
+// maps do not cover rectangular areas.
+func (m *Map) cellBounds(ind int) pixel.Rect {
+	cellSize := pixel.V(float64(m.TileWidth), float64(m.TileHeight))
+	gamePos := indexToGamePos(ind, m.Width, m.Height).Add(m.tileOrigin())
+	return pixel.Rect{Max: cellSize}.Moved(gamePos.ScaledXY(cellSize))
+}
+
+// initialise will load external tilesets, decode all layers and prepare the map for use.  This is done after the map
+// has been decoded from any source format.
+// loader is used to retrieve tilesets, templates and images.
+func (m *Map) initialise(dir string, loader *Loader) error {
+	m.dir = dir
+	m.loader = loader
+
+	for _, g := range m.Groups {
+		g.setGroups()
+	}
+
+	log.WithField("Tileset count", len(m.Tilesets)).Debug("Map.initialise: checking for tileset sources")
+	for i, ts := range m.Tilesets {
+		if ts.Source != "" {
+			sourceTs, err := loader.tileset(dir, ts.Source)
+			if err != nil {
+				log.WithError(err).Error("Map.initialise: could not read tileset source")
+				return err
+			}
+			sourceTs.FirstGID = ts.FirstGID
+			sourceTs.Source = ts.Source
+			m.Tilesets[i] = sourceTs
+		}
+	}
+
+	if err := m.loadTemplates(); err != nil {
+		log.WithError(err).Error("Map.initialise: could not load templates")
+		return err
+	}
+
+	if err := m.decodeLayers(); err != nil {
+		log.WithError(err).Error("Map.initialise: could not decode layers")
+		return err
+	}
+
+	m.setParents()
+
+	tileLayers := m.allTileLayers()
+
+	log.WithField("TileLayer count", len(tileLayers)).Debug("Map.initialise: processing layer tilesets")
+	for _, l := range tileLayers {
+		tileset, isEmpty, usesMultipleTilesets := getTileset(l)
+		if usesMultipleTilesets {
+			log.Debug("Map.initialise: multiple tilesets in use")
+			continue
+		}
+		l.Empty, l.Tileset = isEmpty, tileset
+	}
+
+	for _, l := range tileLayers {
+		l.findAnimatedTiles()
+	}
+
+	// Tiled calculates co-ordinates from the top-left, flipping the y co-ordinate means we match the standard
+	// bottom-left calculation.
+	objectGroups := m.allObjectGroups()
+	log.WithField("Object layer count", len(objectGroups)).Debug("Map.initialise: processing object layers")
+	for _, og := range objectGroups {
+		og.flipY()
+	}
+
+	log.WithField("Tileset count", len(m.Tilesets)).Debug("Map.initialise: processing tilesets")
+	for _, ts := range m.Tilesets {
+		ts.setSprite()
+	}
+
+	return nil
+}
+
+// imageLayerMatrix returns the matrix image layers are drawn to the maps' canvas with.  The matrix shift is because
+// images are drawn from the top-left in Tiled.
+func (m *Map) imageLayerMatrix(l *ImageLayer, camera pixel.Vec) pixel.Matrix {
+	return pixel.IM.Moved(pixel.V(0, m.originY())).Moved(m.ParallaxOffset(l, camera))
+}
+
+// getRenderer returns the renderer set for the map, or the registered renderer if none has been set.
+func (m *Map) getRenderer() Renderer {
+	if m.renderer != nil {
+		return m.renderer
+	}
+	return registeredRenderer()
+}
+
+// getLoader returns the loader the map was read with.  A new loader, which opens files with os.Open, is returned if the
+// map was not read.
+func (m *Map) getLoader() *Loader {
+	if m == nil || m.loader == nil {
+		return NewLoader(nil)
+	}
+	return m.loader
+}
+
+// drawLayers will draw the visible tile and image layers within view to the canvas, in order.  The layers within groups
+// are drawn in place of the group.
+func (m *Map) drawLayers(canvas Canvas, ls []Layer, camera pixel.Vec, view pixel.Rect) error {
+	for _, l := range ls {
+		if !l.IsVisible() {
+			continue
+		}
+
+		switch l := l.(type) {
+		case *TileLayer:
+			// Tile layers are batched, so the parallax offset is applied as the batch is drawn to the canvas.
+			offset := m.ParallaxOffset(l, camera)
+			canvas.SetMatrix(pixel.IM.Moved(offset))
+			err := l.DrawRect(canvas, view.Moved(offset.Scaled(-1)))
+			canvas.SetMatrix(pixel.IM)
+			if err != nil {
+				log.WithError(err).Error("Map.drawLayers: could not draw layer")
+				return err
+			}
+		case *ImageLayer:
+			mat := m.imageLayerMatrix(l, camera)
+			if !rectsOverlap(l.bounds(mat), view) {
+				continue
+			}
+			if err := l.Draw(canvas, mat); err != nil {
+				log.WithError(err).Error("Map.drawLayers: could not draw image layer")
+				return err
+			}
+		case *Group:
+			if err := m.drawLayers(canvas, l.Layers(), camera, view); err != nil {
+				log.WithError(err).Error("Map.drawLayers: could not draw group")
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (m *Map) decodeGID(gid GID) (*DecodedTile, error) {
 	if gid == 0 {
 		return NilTile, nil
@@ -167,13 +800,15 @@ func (m *Map) decodeGID(gid GID) (*DecodedTile, error) {
 
 	for i := len(m.Tilesets) - 1; i >= 0; i-- {
 		if m.Tilesets[i].FirstGID <= gidBare {
+			id := ID(gidBare - m.Tilesets[i].FirstGID)
 			return &DecodedTile{
-				ID:             ID(gidBare - m.Tilesets[i].FirstGID),
+				ID:             id,
 				Tileset:        m.Tilesets[i],
 				HorizontalFlip: gid&gidHorizontalFlip != 0,
 				VerticalFlip:   gid&gidVerticalFlip != 0,
 				DiagonalFlip:   gid&gidDiagonalFlip != 0,
 				Nil:            false,
+				tile:           m.Tilesets[i].GetTile(id),
 			}, nil
 		}
 	}
@@ -184,10 +819,31 @@ func (m *Map) decodeGID(gid GID) (*DecodedTile, error) {
 
 func (m *Map) decodeLayers() error {
 	// Decode tile layers
-	for _, l := range m.TileLayers {
+	decodeTileLayers := m.decodeTileLayers
+	if m.Infinite {
+		decodeTileLayers = m.decodeChunkedTileLayers
+	}
+	if err := decodeTileLayers(); err != nil {
+		log.WithError(err).Error("Map.decodeLayers: could not decode tile layers")
+		return err
+	}
+
+	// Decode object layers
+	for _, og := range m.allObjectGroups() {
+		if err := og.decode(); err != nil {
+			log.WithError(err).Error("Map.decodeLayers: could not decode Object Group")
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Map) decodeTileLayers() error {
+	for _, l := range m.allTileLayers() {
 		gids, err := l.decode(m.Width, m.Height)
 		if err != nil {
-			log.WithError(err).Error("Map.decodeLayers: could not decode layer")
+			log.WithError(err).Error("Map.decodeTileLayers: could not decode layer")
 			return err
 		}
 
@@ -195,19 +851,72 @@ func (m *Map) decodeLayers() error {
 		for j := 0; j < len(gids); j++ {
 			decTile, err := m.decodeGID(gids[j])
 			if err != nil {
-				log.WithError(err).Error("Map.decodeLayers: could not GID")
+				log.WithError(err).Error("Map.decodeTileLayers: could not GID")
 				return err
 			}
 			l.DecodedTiles[j] = decTile
 		}
 	}
 
-	// Decode object layers
-	for _, og := range m.ObjectGroups {
-		if err := og.decode(); err != nil {
-			log.WithError(err).Error("Map.decodeLayers: could not decode Object Group")
+	return nil
+}
+
+// decodeChunkedTileLayers will decode the chunks of all tile layers in an infinite map.  The maps' start, width and
+// height are set to the area covered by all chunks, and each layers' DecodedTiles are filled to cover this area so that
+// they can be used in the same way as those of a finite map.
+func (m *Map) decodeChunkedTileLayers() error {
+	var minX, minY, maxX, maxY int
+	found := false
+	tileLayers := m.allTileLayers()
+
+	for _, l := range tileLayers {
+		if err := l.decodeChunks(); err != nil {
+			log.WithError(err).Error("Map.decodeChunkedTileLayers: could not decode layer chunks")
 			return err
 		}
+
+		for _, c := range l.Data.Chunks {
+			if !found || c.X < minX {
+				minX = c.X
+			}
+			if !found || c.Y < minY {
+				minY = c.Y
+			}
+			if !found || c.X+c.Width > maxX {
+				maxX = c.X + c.Width
+			}
+			if !found || c.Y+c.Height > maxY {
+				maxY = c.Y + c.Height
+			}
+			found = true
+		}
+	}
+
+	m.StartX, m.StartY = minX, minY
+	m.Width, m.Height = maxX-minX, maxY-minY
+	log.WithFields(log.Fields{"Start": fmt.Sprintf("(%d, %d)", m.StartX, m.StartY), "Width": m.Width, "Height": m.Height}).Debug("Map.decodeChunkedTileLayers: calculated map area")
+
+	for _, l := range tileLayers {
+		l.DecodedTiles = make([]*DecodedTile, m.Width*m.Height)
+		for j := range l.DecodedTiles {
+			l.DecodedTiles[j] = NilTile
+		}
+
+		for _, c := range l.Data.Chunks {
+			c.DecodedTiles = make([]*DecodedTile, len(c.gids))
+			for j, gid := range c.gids {
+				decTile, err := m.decodeGID(gid)
+				if err != nil {
+					log.WithError(err).Error("Map.decodeChunkedTileLayers: could not GID")
+					return err
+				}
+				c.DecodedTiles[j] = decTile
+
+				x := c.X - m.StartX + j%c.Width
+				y := c.Y - m.StartY + j/c.Width
+				l.DecodedTiles[y*m.Width+x] = decTile
+			}
+		}
 	}
 
 	return nil
@@ -229,4 +938,7 @@ func (m *Map) setParents() {
 	for _, l := range m.TileLayers {
 		l.setParent(m)
 	}
+	for _, g := range m.Groups {
+		g.setParent(m)
+	}
 }
@@ -1,13 +1,20 @@
 package tilepix_test
 
 import (
+	"bytes"
+	"fmt"
 	"image/color"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	_ "image/png"
 
 	"github.com/bcvery1/tilepix"
+	_ "github.com/bcvery1/tilepix/pixelgl"
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/pixelgl"
 )
@@ -159,3 +166,791 @@ func BenchmarkMap_DrawAll(b *testing.B) {
 		}
 	})
 }
+
+func TestMap_DrawRect(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/render.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &countingRenderer{}
+	m.SetRenderer(r)
+	target := pixelgl.NewCanvas(pixel.R(0, 0, 100, 100))
+
+	view := pixel.R(8, 8, 40, 30)
+	if err := m.DrawRect(target, color.Transparent, pixel.IM, view.Center(), view); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.canvases[0].Bounds(); got != view {
+		t.Errorf("Expected a canvas covering %v, got %v", view, got)
+	}
+
+	// The canvas is resized to draw the whole map, rather than created again.
+	if err := m.DrawAllWithCamera(target, color.Transparent, pixel.IM, view.Center()); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.canvases[0].Bounds(); got != m.Bounds() {
+		t.Errorf("Expected a canvas covering %v, got %v", m.Bounds(), got)
+	}
+	if r.created != 1 {
+		t.Errorf("Expected one canvas to be created, got %d", r.created)
+	}
+}
+
+func TestMap_DrawAll_largeMap(t *testing.T) {
+	// The map is 2400x2400 pixels, so is split between four canvases.
+	m := largeMap(t, 150)
+	r := &countingRenderer{}
+	m.SetRenderer(r)
+	target := pixelgl.NewCanvas(pixel.R(0, 0, 100, 100))
+
+	if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []pixel.Rect{
+		pixel.R(0, 0, 1200, 1200),
+		pixel.R(1200, 0, 2400, 1200),
+		pixel.R(0, 1200, 1200, 2400),
+		pixel.R(1200, 1200, 2400, 2400),
+	}
+	var got []pixel.Rect
+	for _, c := range r.canvases {
+		got = append(got, c.Bounds())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected canvases covering %v, got %v", want, got)
+	}
+
+	// Drawing a smaller area reuses the first canvas.
+	view := pixel.R(100, 100, 740, 500)
+	if err := m.DrawRect(target, color.Transparent, pixel.IM, view.Center(), view); err != nil {
+		t.Fatal(err)
+	}
+	if r.created != 4 || r.canvases[0].Bounds() != view {
+		t.Errorf("Expected the first canvas to be resized to %v, got %v with %d canvases created", view, r.canvases[0].Bounds(), r.created)
+	}
+}
+
+// largeMap returns an orthogonal map of size by size tiles, from testdata/tileset.tsx.
+func largeMap(tb testing.TB, size int) *tilepix.Map {
+	var data strings.Builder
+	for i := 0; i < size*size; i++ {
+		if i > 0 {
+			data.WriteByte(',')
+		}
+		data.WriteString(strconv.Itoa(i%15 + 1))
+	}
+
+	tmx := fmt.Sprintf(`<map version="1.2" orientation="orthogonal" renderorder="right-down" width="%d" height="%d" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" source="tileset.tsx"/>
+ <layer name="Ground" width="%d" height="%d"><data encoding="csv">%s</data></layer>
+</map>`, size, size, size, size, data.String())
+
+	m, err := tilepix.Read(strings.NewReader(tmx), "testdata", nil)
+	if err != nil {
+		tb.Fatalf("Could not create TilePix map: %v", err)
+	}
+	return m
+}
+
+func BenchmarkMap_DrawRect(b *testing.B) {
+	m := largeMap(b, 1000)
+
+	target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 640, 400)})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// A view of 40x25 tiles, which moves each frame.
+	view := func(i int) pixel.Rect {
+		return pixel.R(0, 0, 640, 400).Moved(pixel.V(float64(i%1000), float64(i%1000)))
+	}
+
+	b.Run("DrawAll", func(bb *testing.B) {
+		for i := 0; i < bb.N; i++ {
+			_ = m.DrawAllWithCamera(target, color.Transparent, pixel.IM, view(i).Center())
+		}
+	})
+	b.Run("DrawRect", func(bb *testing.B) {
+		for i := 0; i < bb.N; i++ {
+			v := view(i)
+			_ = m.DrawRect(target, color.Transparent, pixel.IM, v.Center(), v)
+		}
+	})
+}
+
+func TestMap_Infinite(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/infinite-chunks.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.StartX != -16 || m.StartY != -16 {
+		t.Errorf("Expected map start (-16, -16), got (%d, %d)", m.StartX, m.StartY)
+	}
+	if m.Width != 32 || m.Height != 32 {
+		t.Errorf("Expected map size 32x32, got %dx%d", m.Width, m.Height)
+	}
+
+	expBounds := pixel.R(-256, -256, 256, 256)
+	if rect := m.Bounds(); !rect.Min.Eq(expBounds.Min) || !rect.Max.Eq(expBounds.Max) {
+		t.Errorf("Expected bounds %v, got %v", expBounds, rect)
+	}
+
+	// Each layer holds the same chunks, using a different encoding.
+	for _, name := range []string{"xml", "csv", "base64", "base64-gzip", "base64-zlib", "base64-zstd"} {
+		t.Run(name, func(t *testing.T) {
+			l := m.GetTileLayerByName(name)
+			if l == nil {
+				t.Fatalf("no tile layer by name '%s'", name)
+			}
+
+			if len(l.Data.Chunks) != 2 {
+				t.Fatalf("Expected 2 chunks, got %d", len(l.Data.Chunks))
+			}
+			if c := l.Data.Chunks[0]; c.X != -16 || c.Y != -16 || len(c.DecodedTiles) != 256 {
+				t.Errorf("Unexpected first chunk %v with %d tiles", c, len(c.DecodedTiles))
+			}
+			if len(l.DecodedTiles) != m.Width*m.Height {
+				t.Fatalf("Expected %d decoded tiles, got %d", m.Width*m.Height, len(l.DecodedTiles))
+			}
+
+			tests := []struct {
+				index int
+				id    tilepix.ID
+				hFlip bool
+				pos   pixel.Vec
+			}{
+				// Tile (-16,-16), the top-left of the first chunk.
+				{index: 0, id: 0, pos: pixel.V(-248, 248)},
+				// Tile (-15,-15)
+				{index: 33, id: 1, hFlip: true, pos: pixel.V(-232, 232)},
+				// Tile (15,15), the bottom-right of the second chunk.
+				{index: 1023, id: 2, pos: pixel.V(248, -248)},
+			}
+			for _, tt := range tests {
+				dt := l.DecodedTiles[tt.index]
+				if dt.IsNil() || dt.ID != tt.id || dt.HorizontalFlip != tt.hFlip {
+					t.Errorf("Unexpected tile at index %d: %v", tt.index, dt)
+					continue
+				}
+				if pos := dt.Position(tt.index, dt.Tileset); !pos.Eq(tt.pos) {
+					t.Errorf("Expected tile at index %d to be positioned at %v, got %v", tt.index, tt.pos, pos)
+				}
+			}
+		})
+	}
+
+	p, err := m.GetObjectByName("")[0].GetPoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Eq(pixel.V(8, 8)) {
+		t.Errorf("Expected point at %v, got %v", pixel.V(8, 8), p)
+	}
+}
+
+func TestMap_Update(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/animation.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	animated := m.GetTileLayerByName("Animated").DecodedTiles[0]
+	static := m.GetTileLayerByName("Static").DecodedTiles[0]
+
+	// The animation shows tile 0 for 100ms, then tile 1 for 200ms, and loops.
+	tests := []struct {
+		dt   time.Duration
+		want tilepix.ID
+	}{
+		{dt: 0, want: 0},
+		{dt: 99 * time.Millisecond, want: 0},
+		{dt: 1 * time.Millisecond, want: 1},
+		{dt: 199 * time.Millisecond, want: 1},
+		{dt: 1 * time.Millisecond, want: 0},
+		{dt: 150 * time.Millisecond, want: 1},
+	}
+	for _, tt := range tests {
+		m.Update(tt.dt)
+
+		if got := animated.CurrentID(); got != tt.want {
+			t.Errorf("After %v, expected frame %d, got %d", tt.dt, tt.want, got)
+		}
+		if got := static.CurrentID(); got != static.ID {
+			t.Errorf("Expected static tile %d, got %d", static.ID, got)
+		}
+	}
+}
+
+func TestMap_Groups(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/groups.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	level := m.GetGroupByName("Level")
+	if level == nil || level.Parent() != nil {
+		t.Fatalf("Expected top level group 'Level', got %v", level)
+	}
+	if g := m.GetGroupByName("Level/Background"); g == nil || g.Parent() != level {
+		t.Fatalf("Expected group 'Level/Background' in 'Level', got %v", g)
+	}
+
+	terrain := m.GetTileLayerByName("Terrain")
+	if terrain == nil || terrain.Parent() != level {
+		t.Fatalf("Expected tile layer 'Terrain' in 'Level', got %v", terrain)
+	}
+	if terrain != m.GetTileLayerByName("Level/Terrain") {
+		t.Error("Expected tile layer to be found by path")
+	}
+	if dt := terrain.DecodedTiles[1]; dt.IsNil() || dt.ID != 1 {
+		t.Errorf("Expected nested layer to be decoded, got %v", dt)
+	}
+	if got := terrain.TotalOffset(); !got.Eq(pixel.V(10, -20)) {
+		t.Errorf("Expected terrain offset %v, got %v", pixel.V(10, -20), got)
+	}
+	if got := terrain.TotalOpacity(); got != 0.25 {
+		t.Errorf("Expected terrain opacity 0.25, got %v", got)
+	}
+	if got := terrain.GetProperty("music"); got == nil || got.Value != "terrain.ogg" {
+		t.Errorf("Expected terrain to override property, got %v", got)
+	}
+	if got := terrain.GetProperty("difficulty"); got == nil || got.Value != "hard" {
+		t.Errorf("Expected terrain to inherit property, got %v", got)
+	}
+
+	// The top level object layer is found by its name, the nested layer with the same name by its path.
+	if og := m.GetObjectLayerByName("Enemies"); og == nil || og.Parent() != nil {
+		t.Errorf("Expected top level object layer, got %v", og)
+	}
+	enemies := m.GetObjectLayerByName("Level/Enemies")
+	if enemies == nil || enemies.Parent() != level {
+		t.Fatalf("Expected object layer 'Level/Enemies' in 'Level', got %v", enemies)
+	}
+	r, err := m.GetObjectByName("Nested")[0].GetRect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The object is offset by both the group and its' layer; 11 right and 22 down.
+	if exp := pixel.R(11, -22, 27, -6); !r.Min.Eq(exp.Min) || !r.Max.Eq(exp.Max) {
+		t.Errorf("Expected nested object at %v, got %v", exp, r)
+	}
+
+	sky := m.GetImageLayerByName("Level/Background/Sky")
+	if sky == nil {
+		t.Fatal("Expected image layer to be found by path")
+	}
+	if !sky.Visible || sky.IsVisible() {
+		t.Errorf("Expected image layer to be hidden by its' group, got visible %t and %t", sky.Visible, sky.IsVisible())
+	}
+	if !terrain.IsVisible() || !m.GetTileLayerByName("Ground").IsVisible() {
+		t.Error("Expected tile layers to be visible")
+	}
+}
+
+func TestMap_Layers(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/layers.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	written, err := tilepix.Read(&buf, "testdata", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		m    *tilepix.Map
+	}{
+		{name: "read", m: m},
+		{name: "written", m: written},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, want := layerNames(tt.m.Layers()), []string{"Background", "Terrain", "Objects", "Overlay"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected layers %v, got %v", want, got)
+			}
+
+			overlay, ok := tt.m.Layers()[3].(*tilepix.Group)
+			if !ok {
+				t.Fatalf("Expected last layer to be a group, got %T", tt.m.Layers()[3])
+			}
+			if got, want := layerNames(overlay.Layers()), []string{"Clouds", "Roofs"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected group layers %v, got %v", want, got)
+			}
+		})
+	}
+
+	// Layers added after reading follow those read from the map.
+	if err := m.GenerateTileObjectLayer(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := layerNames(m.Layers()), []string{"Background", "Terrain", "Objects", "Overlay", "tileset-objectgroup"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected layers %v, got %v", want, got)
+	}
+}
+
+func layerNames(ls []tilepix.Layer) []string {
+	var names []string
+	for _, l := range ls {
+		names = append(names, l.GetName())
+	}
+	return names
+}
+
+func TestMap_Collection(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/collection.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := m.GetTileLayerByName("Tile Layer 1")
+	if l.Tileset == nil || !l.Tileset.IsCollection() {
+		t.Fatalf("Expected layer tileset to be a collection, got %v", l.Tileset)
+	}
+
+	// Tiles of each size are anchored to the bottom-left of their cell.
+	tests := []struct {
+		index int
+		id    tilepix.ID
+		want  pixel.Vec
+	}{
+		{index: 12, id: 0, want: pixel.V(16, 16)},
+		{index: 14, id: 1, want: pixel.V(32+37.5, 37.5)},
+	}
+	for _, tt := range tests {
+		dt := l.DecodedTiles[tt.index]
+		if dt.ID != tt.id {
+			t.Errorf("Expected tile %d at index %d, got %d", tt.id, tt.index, dt.ID)
+		}
+		if got := dt.Position(tt.index, l.Tileset); !got.Eq(tt.want) {
+			t.Errorf("Expected tile %d at %v, got %v", tt.id, tt.want, got)
+		}
+	}
+
+	tile, err := m.GetObjectLayerByName("Object Layer 1").Objects[0].GetTile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tile.ID != 1 || tile.Tileset.Name != "collection" {
+		t.Errorf("Expected tile 1 from the collection, got %v from %v", tile, tile.Tileset)
+	}
+
+	target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+		t.Fatalf("Could not draw map: %v", err)
+	}
+}
+
+func TestMap_Isometric(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/isometric.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expBounds := pixel.R(0, 0, 112, 56)
+	if rect := m.Bounds(); !rect.Min.Eq(expBounds.Min) || !rect.Max.Eq(expBounds.Max) {
+		t.Errorf("Expected bounds %v, got %v", expBounds, rect)
+	}
+
+	conversions := []struct {
+		tile  pixel.Vec
+		world pixel.Vec
+	}{
+		{tile: pixel.V(0, 0), world: pixel.V(48, 56)},
+		{tile: pixel.V(1, 1), world: pixel.V(48, 40)},
+		{tile: pixel.V(4, 0), world: pixel.V(112, 24)},
+		{tile: pixel.V(0, 3), world: pixel.V(0, 32)},
+		{tile: pixel.V(4, 3), world: pixel.V(64, 0)},
+	}
+	for _, c := range conversions {
+		if got := m.TileToWorld(c.tile); !got.Eq(c.world) {
+			t.Errorf("Expected tile %v at %v, got %v", c.tile, c.world, got)
+		}
+		if got := m.WorldToTile(c.world); !got.Eq(c.tile) {
+			t.Errorf("Expected position %v in tile %v, got %v", c.world, c.tile, got)
+		}
+	}
+
+	// Tiles are anchored to the bottom corner of their diamond.
+	l := m.GetTileLayerByName("Tile Layer 1")
+	positions := []struct {
+		index int
+		want  pixel.Vec
+	}{
+		{index: 0, want: pixel.V(48, 56)},
+		{index: 5, want: pixel.V(48, 40)},
+		{index: 11, want: pixel.V(64, 16)},
+	}
+	for _, p := range positions {
+		if got := l.DecodedTiles[p.index].Position(p.index, l.Tileset); !got.Eq(p.want) {
+			t.Errorf("Expected tile %d at %v, got %v", p.index, p.want, got)
+		}
+	}
+
+	objects := []struct {
+		name string
+		want pixel.Vec
+	}{
+		{name: "Centre", want: pixel.V(48, 40)},
+		{name: "Corner", want: pixel.V(64, 0)},
+	}
+	for _, o := range objects {
+		got, err := m.GetObjectByName(o.name)[0].GetPoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Eq(o.want) {
+			t.Errorf("Expected object '%s' at %v, got %v", o.name, o.want, got)
+		}
+	}
+}
+
+func TestMap_Staggered(t *testing.T) {
+	tests := []struct {
+		name       string
+		filepath   string
+		bounds     pixel.Rect
+		tiles      []pixel.Vec
+		worlds     []pixel.Vec
+		positions  map[int]pixel.Vec
+		neighbours map[pixel.Vec][]pixel.Vec
+	}{
+		{
+			name:     "hexagonal",
+			filepath: "testdata/hexagonal.tmx",
+			bounds:   pixel.R(0, 0, 144, 70),
+			tiles:    []pixel.Vec{pixel.V(0, 0), pixel.V(0, 1), pixel.V(1, 2)},
+			worlds:   []pixel.Vec{pixel.V(0, 70), pixel.V(16, 49), pixel.V(32, 28)},
+			positions: map[int]pixel.Vec{
+				0:  pixel.V(16, 58),
+				5:  pixel.V(64, 37),
+				11: pixel.V(112, 16),
+			},
+			neighbours: map[pixel.Vec][]pixel.Vec{
+				pixel.V(1, 1): {pixel.V(1, 0), pixel.V(2, 0), pixel.V(1, 2), pixel.V(2, 2), pixel.V(0, 1), pixel.V(2, 1)},
+				pixel.V(1, 2): {pixel.V(0, 1), pixel.V(1, 1), pixel.V(0, 3), pixel.V(1, 3), pixel.V(0, 2), pixel.V(2, 2)},
+			},
+		},
+		{
+			name:     "staggered",
+			filepath: "testdata/staggered.tmx",
+			bounds:   pixel.R(0, 0, 80, 56),
+			tiles:    []pixel.Vec{pixel.V(0, 0), pixel.V(1, 0), pixel.V(2, 1)},
+			worlds:   []pixel.Vec{pixel.V(0, 48), pixel.V(16, 56), pixel.V(32, 32)},
+			positions: map[int]pixel.Vec{
+				0:  pixel.V(16, 48),
+				1:  pixel.V(32, 56),
+				11: pixel.V(64, 24),
+			},
+			neighbours: map[pixel.Vec][]pixel.Vec{
+				pixel.V(1, 1): {pixel.V(0, 0), pixel.V(2, 0), pixel.V(0, 1), pixel.V(2, 1)},
+				pixel.V(2, 1): {pixel.V(1, 1), pixel.V(3, 1), pixel.V(1, 2), pixel.V(3, 2)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tilepix.ReadFile(tt.filepath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if rect := m.Bounds(); !rect.Min.Eq(tt.bounds.Min) || !rect.Max.Eq(tt.bounds.Max) {
+				t.Errorf("Expected bounds %v, got %v", tt.bounds, rect)
+			}
+
+			for i, tile := range tt.tiles {
+				if got := m.TileToWorld(tile); !got.Eq(tt.worlds[i]) {
+					t.Errorf("Expected tile %v at %v, got %v", tile, tt.worlds[i], got)
+				}
+			}
+
+			// Positions within each tile must convert back to the same tile.
+			for y := 0; y < m.Height; y++ {
+				for x := 0; x < m.Width; x++ {
+					for _, rel := range []pixel.Vec{pixel.V(0.5, 0.5), pixel.V(0.5, 0.1), pixel.V(0.1, 0.5), pixel.V(0.9, 0.5), pixel.V(0.5, 0.9)} {
+						tile := pixel.V(float64(x), float64(y)).Add(rel)
+						if got := m.WorldToTile(m.TileToWorld(tile)); got.Sub(tile).Len() > 1e-9 {
+							t.Errorf("Expected tile %v, got %v", tile, got)
+						}
+					}
+				}
+			}
+
+			l := m.GetTileLayerByName("Tile Layer 1")
+			for index, want := range tt.positions {
+				if got := l.DecodedTiles[index].Position(index, l.Tileset); !got.Eq(want) {
+					t.Errorf("Expected tile %d at %v, got %v", index, want, got)
+				}
+			}
+
+			for tile, want := range tt.neighbours {
+				if got := m.Neighbours(tile); !reflect.DeepEqual(got, want) {
+					t.Errorf("Expected neighbours of %v to be %v, got %v", tile, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMap_Templates(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/templates.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.Tilesets) != 2 || m.Tilesets[1].FirstGID != 2 {
+		t.Fatalf("Expected the template tileset to be added after the map tileset, got %v", m.Tilesets)
+	}
+
+	objects := m.GetObjectLayerByName("Object Layer 1").Objects
+
+	tests := []struct {
+		name     string
+		object   *tilepix.Object
+		wantName string
+		wantType string
+		contents string
+	}{
+		{name: "template", object: objects[0], wantName: "chest", wantType: "chest", contents: "gold"},
+		{name: "overridden", object: objects[1], wantName: "silver chest", wantType: "chest", contents: "silver"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := tt.object
+			if o.Name != tt.wantName || o.Type != tt.wantType {
+				t.Errorf("Expected object %s of type %s, got %s of type %s", tt.wantName, tt.wantType, o.Name, o.Type)
+			}
+			if o.Width != 16 || o.Height != 16 {
+				t.Errorf("Expected object size 16x16, got %vx%v", o.Width, o.Height)
+			}
+			if got := o.GetProperty("contents").Value; got != tt.contents {
+				t.Errorf("Expected contents %s, got %s", tt.contents, got)
+			}
+			if locked, err := o.GetProperty("locked").AsBool(); err != nil || !locked {
+				t.Errorf("Expected object to be locked, got %v: %v", locked, err)
+			}
+			if len(o.Properties) != 2 {
+				t.Errorf("Expected 2 properties, got %v", o.Properties)
+			}
+
+			tile, err := o.GetTile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tile.ID != 4 || tile.Tileset != m.Tilesets[1] {
+				t.Errorf("Expected tile 4 from the template tileset, got %v from %v", tile, tile.Tileset)
+			}
+		})
+	}
+
+	if objects[0].GetTemplate() == nil || objects[0].GetTemplate() != objects[1].GetTemplate() {
+		t.Errorf("Expected objects to share a template, got %v and %v", objects[0].GetTemplate(), objects[1].GetTemplate())
+	}
+
+	area := objects[2]
+	if area.Name != "area" || area.Type != "hazard" {
+		t.Errorf("Expected area of type hazard, got %s of type %s", area.Name, area.Type)
+	}
+	if damage, err := area.GetProperty("damage").AsInt(); err != nil || damage != 5 {
+		t.Errorf("Expected damage 5, got %v: %v", damage, err)
+	}
+	points, err := area.GetPolygon()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 {
+		t.Errorf("Expected 3 polygon points, got %v", points)
+	}
+}
+
+func TestMap_Embedded(t *testing.T) {
+	f, err := os.Open("testdata/embedded.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// The map holds all of its' images, so is read from an empty directory.
+	m, err := tilepix.Read(f, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img := m.Tilesets[0].Image; img.Data == nil || img.Data.Compression != "zlib" || img.Format != "png" {
+		t.Errorf("Expected an embedded zlib compressed png, got %v", img)
+	}
+
+	target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+		t.Fatalf("Could not draw map: %v", err)
+	}
+}
+
+func TestMap_TileOffset(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/tileoffset.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := m.Tilesets[0]
+	if ts.TileOffset == nil || ts.TileOffset.X != 4 || ts.TileOffset.Y != 8 {
+		t.Errorf("Expected tile offset 4,8, got %v", ts.TileOffset)
+	}
+	if ts.Grid == nil || ts.Grid.Orientation != "isometric" || ts.Grid.Width != 32 || ts.Grid.Height != 16 {
+		t.Errorf("Expected isometric 32x16 grid, got %v", ts.Grid)
+	}
+	if ts.ObjectAlignment != "center" {
+		t.Errorf("Expected center object alignment, got %s", ts.ObjectAlignment)
+	}
+
+	// Tiles are moved right and down by the tile offset.
+	l := m.GetTileLayerByName("Tile Layer 1")
+	tests := []struct {
+		index int
+		want  pixel.Vec
+	}{
+		{index: 0, want: pixel.V(12, 48)},
+		{index: 15, want: pixel.V(60, 0)},
+	}
+	for _, tt := range tests {
+		if got := l.DecodedTiles[tt.index].Position(tt.index, ts); !got.Eq(tt.want) {
+			t.Errorf("Expected tile %d at %v, got %v", tt.index, tt.want, got)
+		}
+	}
+
+	// The tile object is positioned by its' centre, then moved by the tile offset.
+	o := m.GetObjectLayerByName("Object Layer 1").Objects[0]
+	if got := pixel.V(o.X, o.Y); !got.Eq(pixel.V(28, 16)) {
+		t.Errorf("Expected tile object at %v, got %v", pixel.V(28, 16), got)
+	}
+
+	// Objects on the tile are positioned along the axes of the isometric grid.
+	if err := m.GenerateTileObjectLayer(); err != nil {
+		t.Fatal(err)
+	}
+	p, err := m.GetObjectLayerByName("offset-objectgroup").Objects[0].GetPoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Eq(pixel.V(36, 56)) {
+		t.Errorf("Expected tile point object at %v, got %v", pixel.V(36, 56), p)
+	}
+}
+
+func TestMap_Parallax(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/parallax.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bg, err := m.GetBackgroundColor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (color.RGBA{R: 0x33, G: 0x66, B: 0x99, A: 0xff}); bg != want {
+		t.Errorf("Expected background colour %v, got %v", want, bg)
+	}
+
+	// The camera is 40 pixels right and 16 pixels above the parallax origin.
+	camera := pixel.V(48, 32)
+	tests := []struct {
+		name         string
+		layer        tilepix.Layer
+		wantParallax pixel.Vec
+		wantTint     pixel.RGBA
+		wantOffset   pixel.Vec
+	}{
+		{
+			name:         "Ground",
+			layer:        m.GetTileLayerByName("Ground"),
+			wantParallax: pixel.V(1, 1),
+			wantTint:     pixel.RGB(1, 0, 0),
+			wantOffset:   pixel.ZV,
+		},
+		{
+			name:         "Sky",
+			layer:        m.GetImageLayerByName("Background/Sky"),
+			wantParallax: pixel.V(0, 0.5),
+			wantTint:     pixel.RGB(0, 1, 0),
+			wantOffset:   pixel.V(40, 8),
+		},
+		{
+			name:         "Hills",
+			layer:        m.GetTileLayerByName("Background/Hills"),
+			wantParallax: pixel.V(0.5, 0.25),
+			wantTint:     pixel.RGB(1, 1, 0),
+			wantOffset:   pixel.V(20, 12),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.layer.TotalParallax(); !got.Eq(tt.wantParallax) {
+				t.Errorf("Expected parallax %v, got %v", tt.wantParallax, got)
+			}
+			if got := tt.layer.TotalTint(); got != tt.wantTint {
+				t.Errorf("Expected tint %v, got %v", tt.wantTint, got)
+			}
+			if got := m.ParallaxOffset(tt.layer, camera); !got.Eq(tt.wantOffset) {
+				t.Errorf("Expected parallax offset %v, got %v", tt.wantOffset, got)
+			}
+		})
+	}
+
+	target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A nil clear colour uses the maps' background colour.
+	if err := m.DrawAllWithCamera(target, nil, pixel.IM, camera); err != nil {
+		t.Fatalf("Could not draw map: %v", err)
+	}
+}
+
+func TestMap_SiblingDirectories(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/levels/siblings.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The template refers to the same tileset as the map, relative to the template.
+	if len(m.Tilesets) != 1 {
+		t.Fatalf("Expected 1 tileset, got %d", len(m.Tilesets))
+	}
+
+	crates := m.GetObjectByName("crate")
+	if len(crates) != 1 {
+		t.Fatalf("Expected 1 crate, got %d", len(crates))
+	}
+	tile, err := crates[0].GetTile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tile.Tileset != m.Tilesets[0] || tile.ID != 3 {
+		t.Errorf("Expected crate to be tile 3 of %v, got tile %d of %v", m.Tilesets[0], tile.ID, tile.Tileset)
+	}
+
+	target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+		t.Fatalf("Could not draw map: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package tilepix
 
 import (
+	"encoding/xml"
 	"fmt"
 
 	"github.com/faiface/pixel"
@@ -32,14 +33,41 @@ type Object struct {
 	Properties []*Property `xml:"properties>property"`
 	Ellipse    *struct{}   `xml:"ellipse"`
 	Point      *struct{}   `xml:"point"`
+	Text       *Text       `xml:"text"`
+	// Template is the path of the template the object was created from, relative to the map.  This is empty if the
+	// object is not created from a template.
+	Template string `xml:"template,attr"`
 
 	objectType ObjectType
 	tile       *DecodedTile
+	// template is the template the object was created from, nil if it is not created from a template.
+	template *Template
 
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
 
+// clone returns a copy of the object.  The template the object was created from is shared.
+func (o *Object) clone() *Object {
+	c := *o
+	c.Properties = cloneProperties(o.Properties)
+	c.tile = nil
+	c.parentMap = nil
+
+	if o.Polygon != nil {
+		c.Polygon = &Polygon{Points: o.Polygon.Points}
+	}
+	if o.PolyLine != nil {
+		c.PolyLine = &PolyLine{Points: o.PolyLine.Points}
+	}
+	if o.Text != nil {
+		t := *o.Text
+		c.Text = &t
+	}
+
+	return &c
+}
+
 // GetEllipse will return a pixel.Circle representation of this object relative to the map (the co-ordinates will match
 // those as drawn in Tiled).  If the object type is not `EllipseObj` this function will return `pixel.C(pixel.ZV, 0)`
 // and an error.
@@ -129,8 +157,22 @@ func (o *Object) GetPolyLine() ([]pixel.Vec, error) {
 	return pixelPoints, nil
 }
 
+// GetText will return the text of this object, along with how it is displayed.  If the object type is not `TextObj`
+// this function will return `nil` and an error.
+func (o *Object) GetText() (*Text, error) {
+	if o.GetType() != TextObj {
+		log.WithError(ErrInvalidObjectType).WithField("Object type", o.GetType()).Error("Object.GetText: object type mismatch")
+		return nil, ErrInvalidObjectType
+	}
+
+	return o.Text, nil
+}
+
 // GetTile will return the object decoded into a DecodedTile struct.  If this
 // object is not a DecodedTile, this function will return `nil` and an error.
+//
+// The position of a tile object is the bottom-left of the tile as it is drawn in Tiled; the object alignment and tile
+// offset of the tileset have been applied.
 func (o *Object) GetTile() (*DecodedTile, error) {
 	if o.GetType() != TileObj {
 		log.WithError(ErrInvalidObjectType).WithField("Object type", o.GetType()).Error("Object.GetTile: object type mismatch")
@@ -138,23 +180,31 @@ func (o *Object) GetTile() (*DecodedTile, error) {
 	}
 
 	if o.tile == nil {
-		// Setting tileset to the first tileset in the map.  Will need updating when dealing with multiple
-		// tilesets.
-		ts := o.parentMap.Tilesets[0]
-
-		o.tile = &DecodedTile{
-			ID:        o.GID,
-			Tileset:   ts,
-			parentMap: o.parentMap,
+		tile, err := o.parentMap.decodeGID(GID(o.GID))
+		if err != nil {
+			log.WithError(err).WithField("GID", o.GID).Error("Object.GetTile: could not decode GID")
+			return nil, err
 		}
+		tile.setParent(o.parentMap)
 
-		numRows := ts.Tilecount / ts.Columns
-		o.tile.setSprite(ts.Columns, numRows, ts)
+		ts := tile.Tileset
+		tile.setSprite(ts.Columns, ts.numRows(), ts)
+		o.tile = tile
 	}
 
 	return o.tile, nil
 }
 
+// GetProperty returns the Objects' Property by its name, or nil if there is no such property.
+func (o *Object) GetProperty(name string) *Property {
+	return getProperty(o.Properties, name)
+}
+
+// GetTemplate returns the template the object was created from, or nil if it is not created from a template.
+func (o *Object) GetTemplate() *Template {
+	return o.template
+}
+
 // GetType will return the ObjectType constant type of this object.
 func (o *Object) GetType() ObjectType {
 	return o.objectType
@@ -164,8 +214,114 @@ func (o *Object) String() string {
 	return fmt.Sprintf("Object{%s, Name: '%s'}", o.objectType, o.Name)
 }
 
+// UnmarshalXML decodes an object.  Tiled omits the visible attribute when the object is visible, so this is set before
+// decoding.
+func (o *Object) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type object Object
+	raw := object{Visible: true}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		log.WithError(err).Error("Object.UnmarshalXML: could not decode object")
+		return err
+	}
+
+	*o = Object(raw)
+	return nil
+}
+
+// applyTemplate will merge the template into the object.  Fields which have not been set on the object are taken from
+// the template, and properties which are not set on the object are added from the template.  The object is only
+// visible if both it and the template are visible.
+func (o *Object) applyTemplate(t *Template) {
+	o.template = t
+	to := t.Object
+
+	if o.Name == "" {
+		o.Name = to.Name
+	}
+	if o.Type == "" {
+		o.Type = to.Type
+	}
+	if o.Width == 0 {
+		o.Width = to.Width
+	}
+	if o.Height == 0 {
+		o.Height = to.Height
+	}
+	if o.GID == 0 {
+		o.GID = to.GID
+	}
+	o.Visible = o.Visible && to.Visible
+
+	if o.Polygon == nil && o.PolyLine == nil && o.Ellipse == nil && o.Point == nil && o.Text == nil {
+		// Shapes are copied, as their points are converted to game co-ordinates for each object.
+		if to.Polygon != nil {
+			o.Polygon = &Polygon{Points: to.Polygon.Points}
+		}
+		if to.PolyLine != nil {
+			o.PolyLine = &PolyLine{Points: to.PolyLine.Points}
+		}
+		if to.Text != nil {
+			text := *to.Text
+			o.Text = &text
+		}
+		o.Ellipse = to.Ellipse
+		o.Point = to.Point
+	}
+
+	for _, p := range to.Properties {
+		if getProperty(o.Properties, p.Name) == nil {
+			o.Properties = append(o.Properties, p)
+		}
+	}
+}
+
+// flipY will convert the objects' position from Tiled co-ordinates to game co-ordinates.  For orthogonal maps the
+// position becomes the bottom-left of the object.  Isometric maps measure object positions along the axes of the grid,
+// in units of the tile height, so the position is projected onto the map the same as in Tiled.
 func (o *Object) flipY() {
-	o.Y = o.parentMap.pixelHeight() - o.Y - o.Height
+	m := o.parentMap
+
+	pos := pixel.V(o.X, m.originY()-o.Y)
+	if m.isIsometric() {
+		th := float64(m.TileHeight)
+		pos = m.TileToWorld(pixel.V(o.X/th, o.Y/th))
+	}
+
+	pos = pos.Add(o.anchor())
+	o.X, o.Y = pos.X, pos.Y
+}
+
+// anchor returns the offset from the objects' position in Tiled to the bottom-left of the object, in game co-ordinates.
+// Tiled positions tile objects by the object alignment of their tileset, and draws them with the tile offset of their
+// tileset; all other objects are positioned by their top-left, or their top corner in isometric maps.
+func (o *Object) anchor() pixel.Vec {
+	if o.GetType() == TileObj {
+		if tile, err := o.parentMap.decodeGID(GID(o.GID)); err == nil {
+			ts := tile.Tileset
+
+			// The alignment point, from the top-left of the object with Y pointing down.
+			var x, y float64
+			switch ts.objectAlignment() {
+			case "top", "center", "bottom":
+				x = o.Width / 2
+			case "topright", "right", "bottomright":
+				x = o.Width
+			}
+			switch ts.objectAlignment() {
+			case "left", "center", "right":
+				y = o.Height / 2
+			case "bottomleft", "bottom", "bottomright":
+				y = o.Height
+			}
+
+			return pixel.V(-x, y-o.Height).Add(ts.drawOffset())
+		}
+	}
+
+	if o.parentMap.isIsometric() {
+		return pixel.ZV
+	}
+	return pixel.V(0, -o.Height)
 }
 
 // hydrateType will work out what type this object is.
@@ -190,6 +346,11 @@ func (o *Object) hydrateType() {
 		return
 	}
 
+	if o.Text != nil {
+		o.objectType = TextObj
+		return
+	}
+
 	if o.GID != 0 {
 		o.objectType = TileObj
 		return
@@ -9,6 +9,8 @@ import (
 
 	"github.com/bcvery1/tilepix"
 	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
 )
 
 func TestObject_GetEllipse(t *testing.T) {
@@ -234,7 +236,7 @@ func TestObject_GetTile(t *testing.T) {
 			name:   "getting tile",
 			object: o,
 			want: &tilepix.DecodedTile{
-				ID: 1,
+				ID: 0,
 			},
 			wantErr: false,
 		},
@@ -261,3 +263,69 @@ func TestObject_GetTile(t *testing.T) {
 		})
 	}
 }
+
+func TestObject_GetText(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/text.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want tilepix.Text
+	}{
+		{
+			name: "sign",
+			want: tilepix.Text{
+				Text:       "Hello World",
+				FontFamily: "sans-serif",
+				PixelSize:  16,
+				Kerning:    true,
+				HAlign:     "left",
+				VAlign:     "top",
+			},
+		},
+		{
+			name: "dialogue",
+			want: tilepix.Text{
+				Text:       "Welcome to the village, traveller",
+				FontFamily: "Serif",
+				PixelSize:  12,
+				Wrap:       true,
+				Color:      "#ff0000",
+				Bold:       true,
+				Italic:     true,
+				HAlign:     "center",
+				VAlign:     "bottom",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := m.GetObjectByName(tt.name)[0]
+			if o.GetType() != tilepix.TextObj {
+				t.Fatalf("Expected a text object, got %v", o.GetType())
+			}
+
+			got, err := o.GetText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("Object.GetText() = %+v, want %+v", *got, tt.want)
+			}
+
+			target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := o.DrawText(target, text.Atlas7x13, pixel.IM); err != nil {
+				t.Errorf("Object.DrawText() error = %v", err)
+			}
+		})
+	}
+
+	if _, err := m.GetObjectByName("sign")[0].GetRect(); err != tilepix.ErrInvalidObjectType {
+		t.Errorf("Expected text object not to be a rectangle, got %v", err)
+	}
+}
@@ -61,12 +61,20 @@ func TestObject_String(t *testing.T) {
 			want: "Object{Tile, Name: 'object 6'}",
 		},
 		{
-			name: "Unknown",
+			name: "Text",
 			fields: fields{
 				Name:       "object 7",
-				objectType: 6,
+				objectType: TextObj,
+			},
+			want: "Object{Text, Name: 'object 7'}",
+		},
+		{
+			name: "Unknown",
+			fields: fields{
+				Name:       "object 8",
+				objectType: 7,
 			},
-			want: "Object{Unknown, Name: 'object 7'}",
+			want: "Object{Unknown, Name: 'object 8'}",
 		},
 	}
 	for _, tt := range tests {
@@ -1,6 +1,13 @@
 package tilepix
 
-import "fmt"
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/faiface/pixel"
+
+	log "github.com/sirupsen/logrus"
+)
 
 /*
    ___  _     _        _    ___
@@ -12,15 +19,25 @@ import "fmt"
 
 // ObjectGroup is a TMX file structure holding a Tiled ObjectGroup.
 type ObjectGroup struct {
-	Name       string      `xml:"name,attr"`
-	Color      string      `xml:"color,attr"`
-	OffSetX    float64     `xml:"offsetx,attr"`
-	OffSetY    float64     `xml:"offsety,attr"`
-	Opacity    float32     `xml:"opacity,attr"`
-	Visible    bool        `xml:"visible,attr"`
+	Name    string  `xml:"name,attr"`
+	Color   string  `xml:"color,attr"`
+	OffSetX float64 `xml:"offsetx,attr"`
+	OffSetY float64 `xml:"offsety,attr"`
+	Opacity float32 `xml:"opacity,attr"`
+	Visible bool    `xml:"visible,attr"`
+	// TintColor is the Tiled colour string the layers' tile objects are multiplied by, empty if the layer is not
+	// tinted.
+	TintColor string `xml:"tintcolor,attr"`
+	// ParallaxX and ParallaxY are the factors the layer scrolls by, relative to the camera.  A factor of 1 scrolls with
+	// the map.
+	ParallaxX  float64     `xml:"parallaxx,attr"`
+	ParallaxY  float64     `xml:"parallaxy,attr"`
 	Properties []*Property `xml:"properties>property"`
 	Objects    []*Object   `xml:"object"`
 
+	// group is the group which contains this layer, nil if it is at the top level of the map.
+	group *Group
+
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
@@ -29,14 +46,65 @@ func (og *ObjectGroup) String() string {
 	return fmt.Sprintf("ObjectGroup{Name: %s, Properties: %v, Objects: %v}", og.Name, og.Properties, og.Objects)
 }
 
+// clone returns a copy of the object group and its' objects, or nil if the object group is nil.
+func (og *ObjectGroup) clone() *ObjectGroup {
+	if og == nil {
+		return nil
+	}
+
+	c := *og
+	c.Properties = cloneProperties(og.Properties)
+	c.group = nil
+	c.parentMap = nil
+
+	c.Objects = nil
+	for _, o := range og.Objects {
+		c.Objects = append(c.Objects, o.clone())
+	}
+
+	return &c
+}
+
+// TotalOffset returns the offset of the layer, combined with the offsets of the groups which contain it.  The offset is
+// in game co-ordinates, so the Y component is negated from the offset set in Tiled.
+func (og *ObjectGroup) TotalOffset() pixel.Vec {
+	return pixel.V(og.OffSetX, -og.OffSetY).Add(og.group.TotalOffset())
+}
+
+// TotalOpacity returns the opacity of the layer, multiplied by the opacity of the groups which contain it.
+func (og *ObjectGroup) TotalOpacity() float64 {
+	return float64(og.Opacity) * og.group.TotalOpacity()
+}
+
+// TotalParallax returns the parallax factors of the layer, multiplied by the parallax factors of the groups which
+// contain it.
+func (og *ObjectGroup) TotalParallax() pixel.Vec {
+	return pixel.V(og.ParallaxX, og.ParallaxY).ScaledXY(og.group.TotalParallax())
+}
+
+// TotalTint returns the tint colour of the layer, multiplied by the tint colours of the groups which contain it.
+func (og *ObjectGroup) TotalTint() pixel.RGBA {
+	return tintColor(og.TintColor).Mul(og.group.TotalTint())
+}
+
+// UnmarshalXML decodes an object group.  Tiled omits the opacity, visible and parallax attributes when they are the
+// default values, so these are set before decoding.
+func (og *ObjectGroup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type objectGroup ObjectGroup
+	raw := objectGroup{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		log.WithError(err).Error("ObjectGroup.UnmarshalXML: could not decode object group")
+		return err
+	}
+
+	*og = ObjectGroup(raw)
+	return nil
+}
+
 func (og *ObjectGroup) decode() error {
 	for _, o := range og.Objects {
 		// Have the object decode its' type
 		o.hydrateType()
-
-		// Set the x,y offsets of the layer onto the object
-		o.X += og.OffSetX
-		o.Y -= og.OffSetY
 	}
 
 	return nil
@@ -54,9 +122,51 @@ func (og *ObjectGroup) GetObjectByName(name string) []*Object {
 	return objs
 }
 
+// GetObjectByID returns the ObjectGroups' Object with the given ID, or nil if there is no such object.
+func (og *ObjectGroup) GetObjectByID(id ID) *Object {
+	for _, o := range og.Objects {
+		if o.ID == id {
+			return o
+		}
+	}
+	return nil
+}
+
+// GetName returns the name of the layer.
+func (og *ObjectGroup) GetName() string {
+	return og.Name
+}
+
+// GetProperty returns the ObjectGroups' Property by its name, or nil if there is no such property.  Properties are
+// inherited from the groups which contain this layer.
+func (og *ObjectGroup) GetProperty(name string) *Property {
+	if p := getProperty(og.Properties, name); p != nil {
+		return p
+	}
+	return og.group.GetProperty(name)
+}
+
+// IsVisible returns whether the layer is visible.  A layer is only visible if all of the groups which contain it are
+// visible.
+func (og *ObjectGroup) IsVisible() bool {
+	return og.Visible && og.group.IsVisible()
+}
+
+// Parent returns the group which contains this layer, or nil if it is at the top level of the map.
+func (og *ObjectGroup) Parent() *Group {
+	return og.group
+}
+
+// flipY will convert the position of each object from Tiled co-ordinates to game co-ordinates.  The x,y offsets of the
+// layer, and any groups containing it, are then set onto the object.
 func (og *ObjectGroup) flipY() {
+	offset := og.TotalOffset()
+
 	for _, o := range og.Objects {
 		o.flipY()
+
+		o.X += offset.X
+		o.Y += offset.Y
 	}
 }
 
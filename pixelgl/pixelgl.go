@@ -0,0 +1,27 @@
+// Package pixelgl provides the OpenGL renderer for TilePix maps, using the canvases of
+// `github.com/faiface/pixel/pixelgl`.  Importing this package registers the renderer, so `tilepix.Map.DrawAll` can be
+// used:
+//
+//	import _ "github.com/bcvery1/tilepix/pixelgl"
+//
+// Programs which only need the map data, such as game servers, do not import this package, so do not depend on OpenGL.
+package pixelgl
+
+import (
+	"github.com/bcvery1/tilepix"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+)
+
+func init() {
+	tilepix.RegisterRenderer(Renderer{})
+}
+
+// Renderer creates OpenGL canvases to draw maps to.  The canvases must be created and drawn on the main thread, see
+// `pixelgl.Run`.
+type Renderer struct{}
+
+// NewCanvas returns a `pixelgl.Canvas` covering the bounds.
+func (Renderer) NewCanvas(bounds pixel.Rect) tilepix.Canvas {
+	return pixelgl.NewCanvas(bounds)
+}
@@ -74,5 +74,5 @@ func decodePoints(s string) ([]*Point, error) {
 // flipY will get the inverse Y co-ordinate based on the parent maps' size.  This is because Tiled draws from the
 // top-right instead of the bottom-left.
 func (p *Point) flipY() {
-	p.Y = int(p.parentMap.pixelHeight()) - p.Y
+	p.Y = int(p.parentMap.originY()) - p.Y
 }
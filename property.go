@@ -1,6 +1,14 @@
 package tilepix
 
-import "fmt"
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"path/filepath"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
 
 /*
   ___                       _
@@ -12,17 +20,175 @@ import "fmt"
 
 // Property is a TMX file structure which holds a Tiled property.
 type Property struct {
-	Name  string `xml:"name,attr"`
-	Value string `xml:"value,attr"`
+	Name string `xml:"name,attr"`
+	// Type is the Tiled type of the property; one of "string", "int", "float", "bool", "color", "file", "object" or
+	// "class".  This is empty for properties created by older versions of Tiled, which are always strings.
+	Type string `xml:"type,attr"`
+	// PropertyType is the name of the custom type for "class" properties.
+	PropertyType string `xml:"propertytype,attr"`
+	Value        string `xml:"value,attr"`
+	// Properties holds the members of "class" properties.
+	Properties []*Property `xml:"properties>property"`
 
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
 
+// UnmarshalXML decodes a single Tiled property.  Tiled stores multi-line string values as the text content of the
+// property instead of the value attribute, so this is used as the Value if there is no attribute.  Class properties hold
+// their members as nested properties, and have no Value.
+func (p *Property) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw struct {
+		Name         string      `xml:"name,attr"`
+		Type         string      `xml:"type,attr"`
+		PropertyType string      `xml:"propertytype,attr"`
+		Value        *string     `xml:"value,attr"`
+		Text         string      `xml:",chardata"`
+		Properties   []*Property `xml:"properties>property"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		log.WithError(err).Error("Property.UnmarshalXML: could not decode property")
+		return err
+	}
+
+	p.Name = raw.Name
+	p.Type = raw.Type
+	p.PropertyType = raw.PropertyType
+	p.Properties = raw.Properties
+	switch {
+	case raw.Value != nil:
+		p.Value = *raw.Value
+	case raw.Type != "class" && len(raw.Properties) == 0:
+		// The text of class properties is only the whitespace around their nested properties.
+		p.Value = raw.Text
+	}
+
+	return nil
+}
+
+// AsBool will return the value of the property as a bool.  An error is returned if the value could not be parsed.
+//
+// The value is parsed regardless of the Type of the property, so properties from older versions of Tiled can also be
+// used.  This applies to all `As` functions.
+func (p *Property) AsBool() (bool, error) {
+	if p == nil {
+		return false, ErrPropertyNotFound
+	}
+
+	return strconv.ParseBool(p.Value)
+}
+
+// AsColor will return the value of the property as a color.RGBA.  Tiled stores colours as `#AARRGGBB`, or `#RRGGBB`
+// when fully opaque.  An empty value is returned as a fully transparent colour.
+func (p *Property) AsColor() (color.RGBA, error) {
+	if p == nil {
+		return color.RGBA{}, ErrPropertyNotFound
+	}
+
+	if p.Value == "" {
+		return color.RGBA{}, nil
+	}
+
+	return parseColor(p.Value)
+}
+
+// AsFile will return the value of the property as a file path, relative to the current working directory.  Tiled
+// stores file paths relative to the map.
+func (p *Property) AsFile() (string, error) {
+	if p == nil {
+		return "", ErrPropertyNotFound
+	}
+
+	if p.Value == "" || filepath.IsAbs(p.Value) || p.parentMap == nil {
+		return p.Value, nil
+	}
+
+	return filepath.Join(p.parentMap.dir, p.Value), nil
+}
+
+// AsFloat will return the value of the property as a float64.  An error is returned if the value could not be parsed.
+func (p *Property) AsFloat() (float64, error) {
+	if p == nil {
+		return 0, ErrPropertyNotFound
+	}
+
+	return strconv.ParseFloat(p.Value, 64)
+}
+
+// AsInt will return the value of the property as an int.  An error is returned if the value could not be parsed.
+func (p *Property) AsInt() (int, error) {
+	if p == nil {
+		return 0, ErrPropertyNotFound
+	}
+
+	return strconv.Atoi(p.Value)
+}
+
+// AsObject will return the Object in the map which the property references.  A nil Object is returned if the property
+// does not reference an object; an error is returned if the object cannot be found.
+func (p *Property) AsObject() (*Object, error) {
+	if p == nil {
+		return nil, ErrPropertyNotFound
+	}
+
+	id, err := strconv.ParseUint(p.Value, 10, 32)
+	if err != nil {
+		log.WithError(err).WithField("Value", p.Value).Error("Property.AsObject: could not parse object ID")
+		return nil, err
+	}
+
+	if id == 0 {
+		return nil, nil
+	}
+
+	if p.parentMap == nil {
+		log.WithError(ErrObjectNotFound).Error("Property.AsObject: property has no parent map")
+		return nil, ErrObjectNotFound
+	}
+
+	o := p.parentMap.GetObjectByID(ID(id))
+	if o == nil {
+		log.WithError(ErrObjectNotFound).WithField("ID", id).Error("Property.AsObject: no object with ID")
+		return nil, ErrObjectNotFound
+	}
+
+	return o, nil
+}
+
+// GetProperty returns the member of a "class" property by its name, or nil if there is no such member.
+func (p *Property) GetProperty(name string) *Property {
+	return getProperty(p.Properties, name)
+}
+
 func (p *Property) String() string {
 	return fmt.Sprintf("Property{%s: %s}", p.Name, p.Value)
 }
 
 func (p *Property) setParent(m *Map) {
 	p.parentMap = m
+
+	for _, cp := range p.Properties {
+		cp.setParent(m)
+	}
+}
+
+func getProperty(ps []*Property, name string) *Property {
+	for _, p := range ps {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// cloneProperties returns a copy of the properties, including the members of class properties.
+func cloneProperties(ps []*Property) []*Property {
+	var cs []*Property
+	for _, p := range ps {
+		c := *p
+		c.Properties = cloneProperties(p.Properties)
+		c.parentMap = nil
+		cs = append(cs, &c)
+	}
+	return cs
 }
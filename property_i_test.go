@@ -0,0 +1,74 @@
+package tilepix_test
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"github.com/bcvery1/tilepix"
+)
+
+func TestProperties(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/properties.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := m.GetProperty("lives").AsInt(); err != nil || got != 3 {
+		t.Errorf("lives = %v, %v; want 3", got, err)
+	}
+	if got, err := m.GetProperty("gravity").AsFloat(); err != nil || got != 9.81 {
+		t.Errorf("gravity = %v, %v; want 9.81", got, err)
+	}
+	if got, err := m.GetProperty("night").AsBool(); err != nil || !got {
+		t.Errorf("night = %v, %v; want true", got, err)
+	}
+	if got, err := m.GetProperty("sky").AsColor(); err != nil || got != (color.RGBA{R: 0x20, G: 0x40, B: 0x80, A: 0x80}) {
+		t.Errorf("sky = %v, %v", got, err)
+	}
+	if got, err := m.GetProperty("music").AsFile(); err != nil || got != filepath.Join("testdata", "sounds", "theme.ogg") {
+		t.Errorf("music = %v, %v", got, err)
+	}
+	if p := m.GetProperty("intro"); p.Value != "First line\nSecond line" {
+		t.Errorf("intro = %q", p.Value)
+	}
+	if p := m.GetProperty("title"); p.Type != "" || p.Value != "Properties" {
+		t.Errorf("title = %v (type %q)", p, p.Type)
+	}
+	if p := m.GetProperty("spawn"); p.Type != "class" || p.PropertyType != "Spawn" {
+		t.Errorf("spawn = %v (type %q, propertytype %q)", p, p.Type, p.PropertyType)
+	} else if got, err := p.GetProperty("y").AsInt(); err != nil || got != 5 {
+		t.Errorf("spawn.y = %v, %v; want 5", got, err)
+	}
+	if _, err := m.GetProperty("foo").AsInt(); err != tilepix.ErrPropertyNotFound {
+		t.Errorf("foo error = %v, want %v", err, tilepix.ErrPropertyNotFound)
+	}
+
+	ts := m.Tilesets[0]
+	if p := ts.GetProperty("terrain"); p == nil || p.Value != "dungeon" {
+		t.Errorf("tileset terrain = %v", p)
+	}
+	if got, err := ts.GetTile(4).GetProperty("solid").AsBool(); err != nil || got {
+		t.Errorf("tile solid = %v, %v; want false", got, err)
+	}
+
+	if got, err := m.GetTileLayerByName("Tile Layer 1").GetProperty("depth").AsInt(); err != nil || got != -2 {
+		t.Errorf("depth = %v, %v; want -2", got, err)
+	}
+
+	og := m.GetObjectLayerByName("Object Layer 1")
+	if got, err := og.GetProperty("tint").AsColor(); err != nil || got != (color.RGBA{G: 0xff, A: 0xff}) {
+		t.Errorf("tint = %v, %v", got, err)
+	}
+
+	door := m.GetObjectByName("Door")[0]
+	if o, err := door.GetProperty("target").AsObject(); err != nil || o != m.GetObjectByID(2) || o.Name != "Exit" {
+		t.Errorf("target = %v, %v; want Exit", o, err)
+	}
+	if o, err := door.GetProperty("nothing").AsObject(); err != nil || o != nil {
+		t.Errorf("nothing = %v, %v; want nil", o, err)
+	}
+	if _, err := door.GetProperty("missing").AsObject(); err != tilepix.ErrObjectNotFound {
+		t.Errorf("missing error = %v, want %v", err, tilepix.ErrObjectNotFound)
+	}
+}
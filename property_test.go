@@ -1,6 +1,10 @@
 package tilepix
 
-import "testing"
+import (
+	"encoding/xml"
+	"image/color"
+	"testing"
+)
 
 func TestProperty_String(t *testing.T) {
 	type fields struct {
@@ -33,3 +37,134 @@ func TestProperty_String(t *testing.T) {
 		})
 	}
 }
+
+func TestProperty_UnmarshalXML(t *testing.T) {
+	tests := []struct {
+		name        string
+		xml         string
+		wantValue   string
+		wantMembers int
+	}{
+		{name: "value", xml: `<property name="p" value="v"/>`, wantValue: "v"},
+		{name: "multi-line", xml: "<property name=\"p\">First line\nSecond line</property>", wantValue: "First line\nSecond line"},
+		{
+			name: "class",
+			xml: `<property name="p" type="class" propertytype="Spawn">
+  <properties>
+   <property name="x" type="int" value="1"/>
+   <property name="y" type="int" value="2"/>
+  </properties>
+ </property>`,
+			wantMembers: 2,
+		},
+		{
+			name: "empty class",
+			xml: `<property name="p" type="class" propertytype="Spawn">
+ </property>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Property
+			if err := xml.Unmarshal([]byte(tt.xml), &p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Value != tt.wantValue {
+				t.Errorf("Value = %q, want %q", p.Value, tt.wantValue)
+			}
+			if len(p.Properties) != tt.wantMembers {
+				t.Errorf("Expected %d members, got %v", tt.wantMembers, p.Properties)
+			}
+		})
+	}
+}
+
+func TestProperty_AsColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    color.RGBA
+		wantErr bool
+	}{
+		{name: "opaque", value: "#ff8000", want: color.RGBA{R: 0xff, G: 0x80, A: 0xff}},
+		{name: "with alpha", value: "#80ff0000", want: color.RGBA{R: 0x80, A: 0x80}},
+		{name: "no hash", value: "ff0000ff", want: color.RGBA{B: 0xff, A: 0xff}},
+		{name: "empty", value: "", want: color.RGBA{}},
+		{name: "invalid hex", value: "#zzzzzz", wantErr: true},
+		{name: "invalid length", value: "#fff", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Property{Type: "color", Value: tt.value}
+			got, err := p.AsColor()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AsColor() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("AsColor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProperty_AsNumbers(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		wantInt   int
+		wantFloat float64
+		wantBool  bool
+		wantErr   bool
+	}{
+		{name: "one", value: "1", wantInt: 1, wantFloat: 1, wantBool: true},
+		{name: "zero", value: "0", wantInt: 0, wantFloat: 0, wantBool: false},
+		{name: "negative", value: "-3", wantInt: -3, wantFloat: -3, wantErr: true},
+		{name: "text", value: "foo", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Property{Value: tt.value}
+
+			gotInt, errInt := p.AsInt()
+			gotFloat, errFloat := p.AsFloat()
+			gotBool, errBool := p.AsBool()
+
+			if errInt == nil && gotInt != tt.wantInt {
+				t.Errorf("AsInt() = %v, want %v", gotInt, tt.wantInt)
+			}
+			if errFloat == nil && gotFloat != tt.wantFloat {
+				t.Errorf("AsFloat() = %v, want %v", gotFloat, tt.wantFloat)
+			}
+			if errBool == nil && gotBool != tt.wantBool {
+				t.Errorf("AsBool() = %v, want %v", gotBool, tt.wantBool)
+			}
+			if gotErr := errInt != nil || errFloat != nil || errBool != nil; gotErr != tt.wantErr {
+				t.Errorf("As...() errors = %v, %v, %v, wantErr %v", errInt, errFloat, errBool, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProperty_nil(t *testing.T) {
+	var p *Property
+
+	if _, err := p.AsInt(); err != ErrPropertyNotFound {
+		t.Errorf("AsInt() error = %v, want %v", err, ErrPropertyNotFound)
+	}
+	if _, err := p.AsFloat(); err != ErrPropertyNotFound {
+		t.Errorf("AsFloat() error = %v, want %v", err, ErrPropertyNotFound)
+	}
+	if _, err := p.AsBool(); err != ErrPropertyNotFound {
+		t.Errorf("AsBool() error = %v, want %v", err, ErrPropertyNotFound)
+	}
+	if _, err := p.AsColor(); err != ErrPropertyNotFound {
+		t.Errorf("AsColor() error = %v, want %v", err, ErrPropertyNotFound)
+	}
+	if _, err := p.AsFile(); err != ErrPropertyNotFound {
+		t.Errorf("AsFile() error = %v, want %v", err, ErrPropertyNotFound)
+	}
+	if _, err := p.AsObject(); err != ErrPropertyNotFound {
+		t.Errorf("AsObject() error = %v, want %v", err, ErrPropertyNotFound)
+	}
+}
@@ -0,0 +1,270 @@
+package tilepix
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/faiface/pixel"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+  ___             _
+ | _ \___ _ _  __| |___ _ _
+ |   / -_) ' \/ _` / -_) '_|
+ |_|_\___|_||_\__,_\___|_|
+*/
+
+// RenderOptions control how `Map.RenderImage` renders a map.  The zero value renders the visible tile and image layers
+// over the maps' background colour.
+type RenderOptions struct {
+	// Background is the colour the image is filled with before the layers are drawn.  If nil, the maps' background
+	// colour is used.
+	Background color.Color
+	// Camera is the position of the camera, in game co-ordinates, used for the parallax offsets of the layers.  If nil,
+	// the camera is at the maps' parallax origin, so every layer is drawn in place.
+	Camera *pixel.Vec
+	// TileObjects sets whether the tile objects in the visible object groups are drawn.
+	TileObjects bool
+}
+
+// RenderImage will render the map to an image, without the need for an OpenGL context, so maps can be rendered in tests
+// and asset pipelines.  The visible layers are drawn in the same way as `Map.DrawAll`; tile layers with the tiles' flips,
+// image layers, the offsets, opacity and tint of each layer and of the groups which contain them.  The image covers the
+// maps' bounds, with the top-left of the map at the origin of the image.  A nil opts uses the default options.
+func (m *Map) RenderImage(opts *RenderOptions) (*image.RGBA, error) {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+
+	background := opts.Background
+	if background == nil {
+		bg, err := m.GetBackgroundColor()
+		if err != nil {
+			log.WithError(err).Error("Map.RenderImage: could not get background colour")
+			return nil, err
+		}
+		background = bg
+	}
+
+	camera := m.parallaxOrigin()
+	if opts.Camera != nil {
+		camera = *opts.Camera
+	}
+
+	bounds := m.Bounds()
+	r := &imageRenderer{
+		img:    image.NewRGBA(image.Rect(0, 0, int(math.Ceil(bounds.W())), int(math.Ceil(bounds.H())))),
+		bounds: bounds,
+		opts:   opts,
+	}
+	draw.Draw(r.img, r.img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	if err := r.drawLayers(m, m.Layers(), camera); err != nil {
+		log.WithError(err).Error("Map.RenderImage: could not render layers")
+		return nil, err
+	}
+
+	return r.img, nil
+}
+
+// imageRenderer draws sprites to an image, in the way they are drawn to a pixel target.
+type imageRenderer struct {
+	img *image.RGBA
+	// bounds is the area of the map, in game co-ordinates, which the image covers.
+	bounds pixel.Rect
+	opts   *RenderOptions
+}
+
+// drawLayers will draw the visible layers to the image, in order.  This mirrors `Map.drawLayers`, with the tile objects
+// of object groups also drawn if requested.
+func (r *imageRenderer) drawLayers(m *Map, ls []Layer, camera pixel.Vec) error {
+	for _, l := range ls {
+		if !l.IsVisible() {
+			continue
+		}
+
+		switch l := l.(type) {
+		case *TileLayer:
+			r.drawTileLayer(l, m.ParallaxOffset(l, camera))
+		case *ImageLayer:
+			if err := l.Image.initSprite(); err != nil {
+				log.WithError(err).Error("imageRenderer.drawLayers: could not initialise image sprite")
+				return err
+			}
+			r.drawSprite(l.Image.sprite, l.matrix(m.imageLayerMatrix(l, camera)), l.colorMask())
+		case *ObjectGroup:
+			if !r.opts.TileObjects {
+				continue
+			}
+			if err := r.drawTileObjects(l, m.ParallaxOffset(l, camera)); err != nil {
+				log.WithError(err).Error("imageRenderer.drawLayers: could not draw tile objects")
+				return err
+			}
+		case *Group:
+			if err := r.drawLayers(m, l.Layers(), camera); err != nil {
+				log.WithError(err).Error("imageRenderer.drawLayers: could not draw group")
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// drawTileLayer will draw the tiles of the layer within the image in the maps' render order, as `TileLayer.Draw` does
+// where tiles overlap.
+func (r *imageRenderer) drawTileLayer(l *TileLayer, offset pixel.Vec) {
+	// The area of the image, relative to the layer moved by its' parallax offset.
+	view := r.bounds.Moved(offset.Scaled(-1))
+	offset = offset.Add(l.TotalOffset())
+	mask := l.colorMask()
+
+	for _, tileIndex := range tilesWithin(l, view) {
+		t := l.DecodedTiles[tileIndex]
+		if !t.prepare(tileIndex, t.Tileset.Columns, t.Tileset.numRows(), t.Tileset) {
+			continue
+		}
+		r.drawSprite(t.sprite, t.transform.Moved(offset), mask)
+	}
+}
+
+// tilesWithin returns the indices of the tiles in the layer which intersect rect, in the order they should be drawn.
+// rect is in game co-ordinates, including the offset of the layer.
+func tilesWithin(l *TileLayer, rect pixel.Rect) []int {
+	m := l.parentMap
+
+	// The tiles are positioned relative to the layer, without its' offset.
+	r := rect.Moved(l.TotalOffset().Scaled(-1))
+
+	var order []int
+	for _, tileIndex := range m.drawOrderWithin(m.cellsWithin(r)) {
+		t := l.DecodedTiles[tileIndex]
+		if t.IsNil() || !rectsOverlap(t.bounds(tileIndex, t.Tileset), r) {
+			continue
+		}
+		order = append(order, tileIndex)
+	}
+	return order
+}
+
+// drawTileObjects will draw the visible tile objects of the group, in order, each stretched to fill the object.
+func (r *imageRenderer) drawTileObjects(og *ObjectGroup, offset pixel.Vec) error {
+	mask := og.TotalTint().Scaled(og.TotalOpacity())
+
+	for _, o := range og.Objects {
+		if !o.Visible || o.GetType() != TileObj {
+			continue
+		}
+
+		t, err := o.GetTile()
+		if err != nil {
+			log.WithError(err).Error("imageRenderer.drawTileObjects: could not get tile")
+			return err
+		}
+		if t.sprite == nil {
+			// The tiles' image could not be loaded, this has already been logged.
+			continue
+		}
+
+		r.drawSprite(t.sprite, tileObjectMatrix(o, t).Moved(offset), mask)
+	}
+
+	return nil
+}
+
+// drawSprite will draw the sprite to the image with the matrix, as `pixel.Sprite.DrawColorMask` would.  Each pixel of
+// the image is sampled from the nearest pixel of the sprites' picture, and blended over the image with the
+// premultiplied colour mask applied.
+func (r *imageRenderer) drawSprite(s *pixel.Sprite, mat pixel.Matrix, mask pixel.RGBA) {
+	pd := pixel.PictureDataFromPicture(s.Picture())
+	frame := s.Frame()
+	half := frame.Size().Scaled(0.5)
+
+	// The area of the image the sprite covers.
+	area := pixel.Rect{Min: pixel.V(math.Inf(1), math.Inf(1)), Max: pixel.V(math.Inf(-1), math.Inf(-1))}
+	for _, corner := range []pixel.Vec{
+		pixel.V(-half.X, -half.Y), pixel.V(half.X, -half.Y), pixel.V(half.X, half.Y), pixel.V(-half.X, half.Y),
+	} {
+		p := mat.Project(corner)
+		area.Min = pixel.V(math.Min(area.Min.X, p.X), math.Min(area.Min.Y, p.Y))
+		area.Max = pixel.V(math.Max(area.Max.X, p.X), math.Max(area.Max.Y, p.Y))
+	}
+
+	size := r.img.Bounds().Size()
+	minX := clampInt(int(math.Floor(area.Min.X-r.bounds.Min.X)), 0, size.X)
+	maxX := clampInt(int(math.Ceil(area.Max.X-r.bounds.Min.X)), 0, size.X)
+	minY := clampInt(int(math.Floor(r.bounds.Max.Y-area.Max.Y)), 0, size.Y)
+	maxY := clampInt(int(math.Ceil(r.bounds.Max.Y-area.Min.Y)), 0, size.Y)
+
+	for y := minY; y < maxY; y++ {
+		for x := minX; x < maxX; x++ {
+			// The position within the sprite of the centre of the pixel.
+			p := mat.Unproject(pixel.V(r.bounds.Min.X+float64(x)+0.5, r.bounds.Max.Y-float64(y)-0.5))
+			if p.X < -half.X || p.X >= half.X || p.Y < -half.Y || p.Y >= half.Y {
+				continue
+			}
+
+			r.blend(x, y, pd.Color(frame.Center().Add(p)).Mul(mask))
+		}
+	}
+}
+
+// blend will draw the premultiplied colour over the pixel of the image at x, y.
+func (r *imageRenderer) blend(x, y int, src pixel.RGBA) {
+	if src.A <= 0 {
+		return
+	}
+
+	i := r.img.PixOffset(x, y)
+	pix := r.img.Pix[i : i+4 : i+4]
+	inv := 1 - src.A
+
+	pix[0] = toChannel(src.R + float64(pix[0])/0xff*inv)
+	pix[1] = toChannel(src.G + float64(pix[1])/0xff*inv)
+	pix[2] = toChannel(src.B + float64(pix[2])/0xff*inv)
+	pix[3] = toChannel(src.A + float64(pix[3])/0xff*inv)
+}
+
+// tileObjectMatrix returns the matrix to draw the sprite of a tile object with, so that the tile, with its' flips, fills
+// the object.  Objects without a size are drawn at the size of the tile.
+func tileObjectMatrix(o *Object, t *DecodedTile) pixel.Matrix {
+	size := t.sprite.Frame().Size()
+	mat := pixel.IM
+	if t.DiagonalFlip {
+		mat = mat.Rotated(pixel.ZV, math.Pi/2).ScaledXY(pixel.ZV, pixel.V(1, -1))
+		size = pixel.V(size.Y, size.X)
+	}
+	if t.HorizontalFlip {
+		mat = mat.ScaledXY(pixel.ZV, pixel.V(-1, 1))
+	}
+	if t.VerticalFlip {
+		mat = mat.ScaledXY(pixel.ZV, pixel.V(1, -1))
+	}
+
+	w, h := o.Width, o.Height
+	if w == 0 || h == 0 {
+		w, h = size.X, size.Y
+	}
+
+	return mat.ScaledXY(pixel.ZV, pixel.V(w/size.X, h/size.Y)).Moved(pixel.V(o.X+w/2, o.Y+h/2))
+}
+
+// clampInt returns v limited to the range min to max.
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// toChannel converts a colour channel from the range 0 to 1 to a byte.
+func toChannel(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 0xff))
+}
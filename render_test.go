@@ -0,0 +1,58 @@
+package tilepix
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/faiface/pixel"
+)
+
+func Test_tilesWithin(t *testing.T) {
+	files := []string{
+		"testdata/csv.tmx",
+		"testdata/groups.tmx",
+		"testdata/hexagonal.tmx",
+		"testdata/infinite.tmx",
+		"testdata/isometric.tmx",
+		"testdata/multiple_tilesets.tmx",
+		"testdata/render.tmx",
+		"testdata/staggered.tmx",
+		"testdata/tileoffset.tmx",
+	}
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			m, err := ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b := m.Bounds()
+			size := b.Size()
+			rects := []pixel.Rect{
+				b,
+				b.Moved(size.Scaled(2)),
+				pixel.R(b.Min.X, b.Min.Y, b.Min.X+size.X/3, b.Min.Y+size.Y/3),
+				pixel.R(b.Center().X-size.X/5, b.Center().Y-size.Y/4, b.Center().X+size.X/6, b.Center().Y+size.Y/7),
+				pixel.R(b.Max.X-size.X/4, b.Max.Y-size.Y/2, b.Max.X+10, b.Max.Y+10),
+			}
+
+			for _, l := range m.allTileLayers() {
+				for _, rect := range rects {
+					// Every tile which intersects the rectangle, found by checking each tile in draw order.
+					var want []int
+					r := rect.Moved(l.TotalOffset().Scaled(-1))
+					for _, i := range m.drawOrder() {
+						dt := l.DecodedTiles[i]
+						if !dt.IsNil() && rectsOverlap(dt.bounds(i, dt.Tileset), r) {
+							want = append(want, i)
+						}
+					}
+
+					if got := tilesWithin(l, rect); !reflect.DeepEqual(got, want) {
+						t.Errorf("Layer %s: tilesWithin(%v) = %v, want %v", l.Name, rect, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package tilepix
+
+import (
+	"image/color"
+	"math"
+	"sync"
+
+	"github.com/faiface/pixel"
+)
+
+/*
+  ___             _
+ | _ \___ _ _  __| |___ _ _ ___ _ _
+ |   / -_) ' \/ _` / -_) '_/ -_) '_|
+ |_|_\___|_||_\__,_\___|_| \___|_|
+*/
+
+// Renderer creates the canvases maps are drawn to by `Map.DrawAll`.  Drawing a map to a canvas needs a graphics context,
+// so this is provided by a separate package; the map model itself has no dependency on one.  The OpenGL renderer in the
+// `github.com/bcvery1/tilepix/pixelgl` package registers itself when imported:
+//
+//	import _ "github.com/bcvery1/tilepix/pixelgl"
+type Renderer interface {
+	// NewCanvas returns a canvas covering the bounds, in game co-ordinates.
+	NewCanvas(bounds pixel.Rect) Canvas
+}
+
+// Canvas is an off-screen target which the layers of a map are drawn to, before the canvas is drawn to the target passed
+// to `Map.DrawAll`.  `pixelgl.Canvas` implements Canvas.
+type Canvas interface {
+	pixel.Target
+
+	// Bounds returns the area of the canvas, in game co-ordinates.
+	Bounds() pixel.Rect
+	// SetBounds resizes the canvas to cover the bounds.
+	SetBounds(bounds pixel.Rect)
+	// Clear will fill the canvas with the colour.
+	Clear(c color.Color)
+	// SetMatrix sets the matrix applied to everything drawn to the canvas.
+	SetMatrix(mat pixel.Matrix)
+	// Draw will draw the canvas to the target, with the matrix.
+	Draw(target pixel.Target, mat pixel.Matrix)
+}
+
+// maxCanvasSize is the largest width and height, in pixels, of the canvases maps are drawn to.  Larger areas are split
+// between several canvases; this is within the maximum texture size of most graphics cards.
+const maxCanvasSize = 2048
+
+var (
+	defaultRendererMu sync.RWMutex
+	defaultRenderer   Renderer
+)
+
+// RegisterRenderer sets the renderer used to draw maps which do not have their own renderer set with
+// `Map.SetRenderer`.  This is usually called from the init function of the package providing the renderer.
+func RegisterRenderer(r Renderer) {
+	defaultRendererMu.Lock()
+	defer defaultRendererMu.Unlock()
+
+	defaultRenderer = r
+}
+
+// splitRect will split the rectangle into a grid of equally sized rectangles, each no wider or taller than size.  The
+// rectangles are ordered row by row from the bottom-left.
+func splitRect(r pixel.Rect, size float64) []pixel.Rect {
+	columns := math.Max(1, math.Ceil(r.W()/size))
+	rows := math.Max(1, math.Ceil(r.H()/size))
+	w, h := r.W()/columns, r.H()/rows
+
+	rects := make([]pixel.Rect, 0, int(columns*rows))
+	for y := 0.0; y < rows; y++ {
+		for x := 0.0; x < columns; x++ {
+			min := r.Min.Add(pixel.V(x*w, y*h))
+			rects = append(rects, pixel.Rect{Min: min, Max: min.Add(pixel.V(w, h))})
+		}
+	}
+	return rects
+}
+
+// registeredRenderer returns the renderer set with `RegisterRenderer`, or nil if none has been registered.
+func registeredRenderer() Renderer {
+	defaultRendererMu.RLock()
+	defer defaultRendererMu.RUnlock()
+
+	return defaultRenderer
+}
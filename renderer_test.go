@@ -0,0 +1,68 @@
+package tilepix_test
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/bcvery1/tilepix"
+	tpgl "github.com/bcvery1/tilepix/pixelgl"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+)
+
+// countingRenderer creates pixelgl canvases, counting the number created.
+type countingRenderer struct {
+	created int
+	// canvases holds each canvas created.
+	canvases []*pixelgl.Canvas
+}
+
+func (r *countingRenderer) NewCanvas(bounds pixel.Rect) tilepix.Canvas {
+	r.created++
+	c := pixelgl.NewCanvas(bounds)
+	r.canvases = append(r.canvases, c)
+	return c
+}
+
+func TestMap_SetRenderer(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/render.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := pixelgl.NewCanvas(pixel.R(0, 0, 100, 100))
+
+	r := &countingRenderer{}
+	m.SetRenderer(r)
+	for i := 0; i < 2; i++ {
+		if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.created != 1 {
+		t.Errorf("Expected the map to create one canvas with its' renderer, got %d", r.created)
+	}
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	defer tilepix.RegisterRenderer(tpgl.Renderer{})
+
+	target := pixelgl.NewCanvas(pixel.R(0, 0, 100, 100))
+
+	tilepix.RegisterRenderer(nil)
+	m, err := tilepix.ReadFile("testdata/render.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DrawAll(target, color.Transparent, pixel.IM); err != tilepix.ErrNoRenderer {
+		t.Errorf("Expected ErrNoRenderer without a renderer, got %v", err)
+	}
+
+	r := &countingRenderer{}
+	tilepix.RegisterRenderer(r)
+	if err := m.DrawAll(target, color.Transparent, pixel.IM); err != nil {
+		t.Fatal(err)
+	}
+	if r.created != 1 {
+		t.Errorf("Expected the registered renderer to be used, got %d canvases created", r.created)
+	}
+}
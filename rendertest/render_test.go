@@ -0,0 +1,111 @@
+// Package rendertest_test tests `Map.RenderImage`.  These tests are kept apart from the tests of the tilepix package, which
+// use OpenGL canvases, so they can be run without a GPU or display.
+package rendertest_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	// Required to decode the tileset PNG
+	_ "image/png"
+
+	"github.com/bcvery1/tilepix"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	log.SetOutput(ioutil.Discard)
+}
+
+// loadRGBA decodes the image file at path.
+func loadRGBA(t *testing.T, path string) *image.RGBA {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
+
+func TestMap_RenderImage(t *testing.T) {
+	m, err := tilepix.ReadFile("../testdata/render.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tileset := loadRGBA(t, "../testdata/tileset.png")
+
+	// tile returns the colour of the pixel at x, y of the tile with the ID in testdata/tileset.png.
+	tile := func(id, x, y int) color.RGBA {
+		return tileset.RGBAAt(id%3*16+x, id/3*16+y)
+	}
+	scaled := func(c color.RGBA, s float64) color.RGBA {
+		f := func(v uint8) uint8 { return uint8(float64(v)*s + 0.5) }
+		return color.RGBA{R: f(c.R), G: f(c.G), B: f(c.B), A: f(c.A)}
+	}
+	transparent := func(x, y int) color.RGBA { return color.RGBA{} }
+
+	tests := []struct {
+		name string
+		opts *tilepix.RenderOptions
+		// area is the region of the image to check, each pixel is compared with expected.
+		area     image.Rectangle
+		expected func(x, y int) color.RGBA
+	}{
+		{"tile", nil, image.Rect(0, 0, 16, 16), func(x, y int) color.RGBA { return tile(0, x, y) }},
+		{"horizontal flip", nil, image.Rect(16, 0, 32, 16), func(x, y int) color.RGBA { return tile(1, 15-x, y) }},
+		{"vertical flip", nil, image.Rect(32, 0, 48, 16), func(x, y int) color.RGBA { return tile(2, x, 15-y) }},
+		{"empty", nil, image.Rect(48, 0, 64, 16), transparent},
+		{"opacity", nil, image.Rect(0, 16, 16, 32), func(x, y int) color.RGBA { return scaled(tile(0, x, y), 0.5) }},
+		{"offset", nil, image.Rect(40, 16, 56, 32), func(x, y int) color.RGBA { return tile(4, x, y) }},
+		{"image layer", nil, image.Rect(0, 32, 32, 48), func(x, y int) color.RGBA { return scaled(color.RGBA{255, 255, 255, 255}, 0.25) }},
+		{"tile objects hidden", nil, image.Rect(64, 0, 80, 32), transparent},
+		{
+			"tile objects",
+			&tilepix.RenderOptions{TileObjects: true},
+			image.Rect(64, 0, 80, 32),
+			func(x, y int) color.RGBA { return tile(5, x, y/2) },
+		},
+		{
+			"background",
+			&tilepix.RenderOptions{Background: color.RGBA{10, 20, 30, 255}},
+			image.Rect(16, 16, 32, 32),
+			func(x, y int) color.RGBA { return color.RGBA{10, 20, 30, 255} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := m.RenderImage(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := img.Bounds(); got != image.Rect(0, 0, 80, 48) {
+				t.Fatalf("Expected image bounds %v, got %v", image.Rect(0, 0, 80, 48), got)
+			}
+
+			for y := tt.area.Min.Y; y < tt.area.Max.Y; y++ {
+				for x := tt.area.Min.X; x < tt.area.Max.X; x++ {
+					want := tt.expected(x-tt.area.Min.X, y-tt.area.Min.Y)
+					if got := img.RGBAAt(x, y); got != want {
+						t.Fatalf("Expected %v at %d,%d, got %v", want, x, y, got)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package tilepix
+
+import (
+	"math"
+
+	"github.com/faiface/pixel"
+)
+
+// staggerParams holds the measurements used to position tiles in staggered and hexagonal maps.  These match those used
+// by Tiled, where a staggered map is laid out as a hexagonal map with no side length.  All measurements are in Tiled
+// pixel co-ordinates, from the top-left of the map with Y pointing down.
+type staggerParams struct {
+	tileWidth, tileHeight    float64
+	sideLengthX, sideLengthY float64
+	sideOffsetX, sideOffsetY float64
+	columnWidth, rowHeight   float64
+
+	// staggerX is set when columns are shifted, rather than rows.
+	staggerX bool
+	// staggerEven is set when the even rows or columns are shifted, rather than the odd ones.
+	staggerEven bool
+	// hexagonal is set for hexagonal maps, and not for staggered maps.
+	hexagonal bool
+}
+
+func (m *Map) staggerParams() staggerParams {
+	p := staggerParams{
+		// Tiled rounds the tile size down to an even number of pixels.
+		tileWidth:   float64(m.TileWidth &^ 1),
+		tileHeight:  float64(m.TileHeight &^ 1),
+		staggerX:    m.StaggerAxis == "x",
+		staggerEven: m.StaggerIndex == "even",
+		hexagonal:   m.Orientation == "hexagonal",
+	}
+
+	if p.hexagonal {
+		if p.staggerX {
+			p.sideLengthX = float64(m.HexSideLength)
+		} else {
+			p.sideLengthY = float64(m.HexSideLength)
+		}
+	}
+
+	p.sideOffsetX = (p.tileWidth - p.sideLengthX) / 2
+	p.sideOffsetY = (p.tileHeight - p.sideLengthY) / 2
+	p.columnWidth = p.sideOffsetX + p.sideLengthX
+	p.rowHeight = p.sideOffsetY + p.sideLengthY
+
+	return p
+}
+
+// doStagger returns whether the tile at x, y is in a shifted row or column.
+func (p staggerParams) doStagger(x, y int) bool {
+	i := y
+	if p.staggerX {
+		i = x
+	}
+	return (i&1 == 1) != p.staggerEven
+}
+
+// size returns the size of a map of w by h tiles.
+func (p staggerParams) size(w, h int) pixel.Vec {
+	if p.staggerX {
+		size := pixel.V(float64(w)*p.columnWidth+p.sideOffsetX, float64(h)*(p.tileHeight+p.sideLengthY))
+		if w > 1 {
+			size.Y += p.rowHeight
+		}
+		return size
+	}
+
+	size := pixel.V(float64(w)*(p.tileWidth+p.sideLengthX), float64(h)*p.rowHeight+p.sideOffsetY)
+	if h > 1 {
+		size.X += p.columnWidth
+	}
+	return size
+}
+
+// gridToPixel returns the top-left of the bounding box of the tile at x, y, as if its' row or column were not shifted.
+func (p staggerParams) gridToPixel(x, y int) pixel.Vec {
+	if p.staggerX {
+		return pixel.V(float64(x)*p.columnWidth, float64(y)*(p.tileHeight+p.sideLengthY))
+	}
+	return pixel.V(float64(x)*(p.tileWidth+p.sideLengthX), float64(y)*p.rowHeight)
+}
+
+// tileToPixel returns the top-left of the bounding box of the tile at x, y.
+func (p staggerParams) tileToPixel(x, y int) pixel.Vec {
+	pos := p.gridToPixel(x, y)
+	if p.doStagger(x, y) {
+		if p.staggerX {
+			pos.Y += p.rowHeight
+		} else {
+			pos.X += p.columnWidth
+		}
+	}
+	return pos
+}
+
+// pixelToTile returns the tile which contains the position.  This uses the same method as Tiled; the position is
+// placed within a block of tiles aligned to the grid, then the tile with the nearest centre is found.
+func (p staggerParams) pixelToTile(pos pixel.Vec) (x, y int) {
+	if p.staggerX {
+		if p.staggerEven {
+			pos.X -= p.tileWidth
+		} else {
+			pos.X -= p.sideOffsetX
+		}
+	} else {
+		if p.staggerEven {
+			pos.Y -= p.tileHeight
+		} else {
+			pos.Y -= p.sideOffsetY
+		}
+	}
+
+	refX := int(math.Floor(pos.X / (p.columnWidth * 2)))
+	refY := int(math.Floor(pos.Y / (p.rowHeight * 2)))
+	rel := pos.Sub(pixel.V(float64(refX)*p.columnWidth*2, float64(refY)*p.rowHeight*2))
+
+	var centres [4]pixel.Vec
+	var offsets [4][2]int
+	if p.staggerX {
+		refX *= 2
+		if p.staggerEven {
+			refX++
+		}
+
+		left := p.sideLengthX / 2
+		centreX := left + p.columnWidth
+		centreY := p.tileHeight / 2
+		centres = [4]pixel.Vec{
+			pixel.V(left, centreY),
+			pixel.V(centreX, centreY-p.rowHeight),
+			pixel.V(centreX, centreY+p.rowHeight),
+			pixel.V(centreX+p.columnWidth, centreY),
+		}
+		offsets = [4][2]int{{0, 0}, {1, -1}, {1, 0}, {2, 0}}
+	} else {
+		refY *= 2
+		if p.staggerEven {
+			refY++
+		}
+
+		top := p.sideLengthY / 2
+		centreX := p.tileWidth / 2
+		centreY := top + p.rowHeight
+		centres = [4]pixel.Vec{
+			pixel.V(centreX, top),
+			pixel.V(centreX-p.columnWidth, centreY),
+			pixel.V(centreX+p.columnWidth, centreY),
+			pixel.V(centreX, centreY+p.rowHeight),
+		}
+		offsets = [4][2]int{{0, 0}, {-1, 1}, {0, 1}, {0, 2}}
+	}
+
+	// The tiles in staggered maps are diamonds, rather than hexagons.  Scaling the Y axis makes the diamonds square,
+	// so the nearest centre is the diamond which contains the position.
+	scaleY := 1.0
+	if !p.hexagonal {
+		scaleY = p.tileWidth / p.tileHeight
+	}
+
+	nearest, minDist := 0, math.Inf(1)
+	for i, c := range centres {
+		d := c.Sub(rel)
+		if dist := d.X*d.X + d.Y*d.Y*scaleY*scaleY; dist < minDist {
+			nearest, minDist = i, dist
+		}
+	}
+
+	return refX + offsets[nearest][0], refY + offsets[nearest][1]
+}
+
+// neighbours returns the tiles which share an edge with the tile at x, y.  Tiles in hexagonal maps have six
+// neighbours, those in staggered maps have four.
+func (p staggerParams) neighbours(x, y int) [][2]int {
+	// The offsets to the neighbours in the rows, or columns, either side; these depend on whether the tile has been
+	// shifted.
+	before, after := -1, 0
+	if p.doStagger(x, y) {
+		before, after = 0, 1
+	}
+
+	var ns [][2]int
+	if p.staggerX {
+		ns = [][2]int{{x - 1, y + before}, {x + 1, y + before}, {x - 1, y + after}, {x + 1, y + after}}
+		if p.hexagonal {
+			ns = append(ns, [2]int{x, y - 1}, [2]int{x, y + 1})
+		}
+		return ns
+	}
+
+	ns = [][2]int{{x + before, y - 1}, {x + after, y - 1}, {x + before, y + 1}, {x + after, y + 1}}
+	if p.hexagonal {
+		ns = append(ns, [2]int{x - 1, y}, [2]int{x + 1, y})
+	}
+	return ns
+}
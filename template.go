@@ -0,0 +1,173 @@
+package tilepix
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+  _____                _      _
+ |_   _|__ _ __  _ __ | |__ _| |_ ___
+   | |/ -_) '  \| '_ \| / _` |  _/ -_)
+   |_|\___|_|_|_| .__/|_\__,_|\__\___|
+                |_|
+*/
+
+// Template is a TMX file structure holding a Tiled object template.  Objects created from a template only hold the
+// fields which have been changed for that object; the rest are merged in from the template when the map is read.
+type Template struct {
+	// Source is the path of the template file, relative to the map.
+	Source string `xml:"-"`
+	// Tileset is the tileset used by the templates' object, if it is a tile object.  Once the map has been read, this is
+	// the tileset as it is used by the map, and the objects' GID is relative to this tileset.
+	Tileset *Tileset `xml:"tileset"`
+	Object  *Object  `xml:"object"`
+}
+
+func (t *Template) String() string {
+	return fmt.Sprintf("Template{Source: %s, Object: %v}", t.Source, t.Object)
+}
+
+func readTemplate(r io.Reader) (*Template, error) {
+	log.Debug("readTemplate: reading from io.Reader")
+
+	var t Template
+	if err := xml.NewDecoder(r).Decode(&t); err != nil {
+		log.WithError(err).Error("readTemplate: could not decode to Template")
+		return nil, err
+	}
+
+	if t.Object == nil {
+		log.WithError(ErrInvalidTemplate).Error("readTemplate: template has no object")
+		return nil, ErrInvalidTemplate
+	}
+
+	return &t, nil
+}
+
+// readTemplateSource will open and read an object template, relative to dir, using openFileFunc.  Templates with a
+// `.tj` or `.json` extension are read as Tiled JSON templates, all others are read as TX.
+func readTemplateSource(openFileFunc func(name string) (http.File, error), dir, source string) (*Template, error) {
+	log.WithField("Source", source).Debug("readTemplateSource: reading template source")
+
+	f, err := openFileFunc(filepath.Join(dir, source))
+	if err != nil {
+		log.WithError(err).Error("readTemplateSource: could not open template source")
+		return nil, err
+	}
+	defer f.Close()
+
+	var t *Template
+	if isJSONPath(source) {
+		t, err = readTemplateJSON(f)
+	} else {
+		t, err = readTemplate(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.Source = source
+	return t, nil
+}
+
+// loadTemplates will read the template of each object created from one, and merge the template into the object.
+// Templates used by more than one object are only read once.
+func (m *Map) loadTemplates() error {
+	templates := make(map[string]*Template)
+
+	for _, og := range m.allObjectGroups() {
+		for _, o := range og.Objects {
+			if o.Template == "" {
+				continue
+			}
+
+			source := filepath.Clean(o.Template)
+			t, ok := templates[source]
+			if !ok {
+				var err error
+				t, err = readTemplateSource(m.getLoader().openFileFunc, m.dir, source)
+				if err != nil {
+					log.WithError(err).WithField("Template", o.Template).Error("Map.loadTemplates: could not read template")
+					return err
+				}
+
+				if err := m.addTemplateTileset(t); err != nil {
+					log.WithError(err).WithField("Template", o.Template).Error("Map.loadTemplates: could not add template tileset")
+					return err
+				}
+
+				templates[source] = t
+			}
+
+			o.applyTemplate(t)
+		}
+	}
+
+	return nil
+}
+
+// addTemplateTileset will find the tileset used by the templates' tile object in the map, adding it to the map if it is
+// not already used.  The templates' object GID is then changed to be relative to the maps' tileset.
+func (m *Map) addTemplateTileset(t *Template) error {
+	if t.Tileset == nil || t.Object.GID == 0 {
+		return nil
+	}
+
+	// The tileset source is relative to the template, this finds it relative to the map.
+	source := filepath.Join(filepath.Dir(t.Source), t.Tileset.Source)
+
+	var ts *Tileset
+	for _, mts := range m.Tilesets {
+		if mts.Source != "" && filepath.Clean(mts.Source) == source {
+			ts = mts
+			break
+		}
+	}
+
+	if ts == nil {
+		var err error
+		ts, err = m.getLoader().tileset(m.dir, source)
+		if err != nil {
+			log.WithError(err).Error("Map.addTemplateTileset: could not read tileset source")
+			return err
+		}
+
+		ts.FirstGID = m.nextGID()
+		ts.Source = source
+		m.Tilesets = append(m.Tilesets, ts)
+	}
+
+	gid := GID(t.Object.GID)
+	t.Object.GID = ID((gid&^gidFlip - t.Tileset.FirstGID + ts.FirstGID) | gid&gidFlip)
+	t.Tileset = ts
+
+	return nil
+}
+
+// nextGID returns the first GID after those used by the maps' tilesets.
+func (m *Map) nextGID() GID {
+	next := GID(1)
+	for _, ts := range m.Tilesets {
+		end := ts.FirstGID + GID(ts.Tilecount)
+		for _, t := range ts.Tiles {
+			if gid := ts.FirstGID + GID(t.ID) + 1; gid > end {
+				end = gid
+			}
+		}
+		if end == ts.FirstGID {
+			// The tileset has no tile count, so must use at least its' first GID.
+			end++
+		}
+
+		if end > next {
+			next = end
+		}
+	}
+	return next
+}
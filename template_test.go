@@ -0,0 +1,12 @@
+package tilepix
+
+import "testing"
+
+func TestTemplate_String(t *testing.T) {
+	tmpl := &Template{Source: "chest.tx", Object: &Object{Name: "chest"}}
+
+	want := "Template{Source: chest.tx, Object: " + tmpl.Object.String() + "}"
+	if got := tmpl.String(); got != want {
+		t.Errorf("Template.String() = %v, want %v", got, want)
+	}
+}
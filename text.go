@@ -0,0 +1,166 @@
+package tilepix
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/text"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+  _____        _
+ |_   _|____ _| |_
+   | |/ -_) \ /  _|
+   |_|\___/_\_\\__|
+*/
+
+// Text is a TMX file structure holding the text of a Tiled text object, and how it is displayed.
+type Text struct {
+	Text       string `xml:",chardata"`
+	FontFamily string `xml:"fontfamily,attr"`
+	PixelSize  int    `xml:"pixelsize,attr"`
+	Wrap       bool   `xml:"wrap,attr"`
+	// Color is the Tiled colour string of the text, in the format `#AARRGGBB` or `#RRGGBB`.  Use GetColor to decode it.
+	Color     string `xml:"color,attr"`
+	Bold      bool   `xml:"bold,attr"`
+	Italic    bool   `xml:"italic,attr"`
+	Underline bool   `xml:"underline,attr"`
+	Strikeout bool   `xml:"strikeout,attr"`
+	Kerning   bool   `xml:"kerning,attr"`
+	// HAlign is the horizontal alignment of the text within the object; one of "left", "center", "right" or
+	// "justify".
+	HAlign string `xml:"halign,attr"`
+	// VAlign is the vertical alignment of the text within the object; one of "top", "center" or "bottom".
+	VAlign string `xml:"valign,attr"`
+}
+
+// GetColor returns the colour of the text.  Tiled draws text in black when no colour is set.
+func (t *Text) GetColor() (color.RGBA, error) {
+	if t.Color == "" {
+		return color.RGBA{A: 0xff}, nil
+	}
+	return parseColor(t.Color)
+}
+
+func (t *Text) String() string {
+	return fmt.Sprintf("Text{Text: '%s', FontFamily: %s, PixelSize: %d}", t.Text, t.FontFamily, t.PixelSize)
+}
+
+// UnmarshalXML decodes the text of an object.  Tiled omits the attributes which are the default values, so these are
+// set before decoding.
+func (t *Text) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type objectText Text
+	raw := objectText(defaultText())
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		log.WithError(err).Error("Text.UnmarshalXML: could not decode text")
+		return err
+	}
+
+	*t = Text(raw)
+	return nil
+}
+
+// DrawText will draw the text of the object to the target, within the bounds of the object, using the atlas.  The text
+// is aligned and wrapped as it is in Tiled.  The atlas is used as given, so should be created from the font family and
+// pixel size of the text; see `Object.GetText`.  Nothing is drawn when the object is not visible.  If the object type is
+// not `TextObj` this function will return an error.
+func (o *Object) DrawText(target pixel.Target, atlas *text.Atlas, mat pixel.Matrix) error {
+	t, err := o.GetText()
+	if err != nil {
+		log.WithError(err).Error("Object.DrawText: could not get text")
+		return err
+	}
+
+	if !o.Visible {
+		return nil
+	}
+
+	col, err := t.GetColor()
+	if err != nil {
+		log.WithError(err).Error("Object.DrawText: could not get text colour")
+		return err
+	}
+
+	txt := text.New(pixel.ZV, atlas)
+	txt.Color = col
+
+	measure := func(s string) float64 {
+		return txt.BoundsOf(s).W()
+	}
+
+	var lines []string
+	for _, line := range strings.Split(t.Text, "\n") {
+		if t.Wrap {
+			lines = append(lines, wrapText(line, o.Width, measure)...)
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	// The object position is the bottom-left of the object, the text is positioned from the top.
+	spaceY := o.Height - float64(len(lines))*atlas.LineHeight()
+	top := o.Y + o.Height
+	switch t.VAlign {
+	case "center":
+		top -= spaceY / 2
+	case "bottom":
+		top -= spaceY
+	}
+
+	for i, line := range lines {
+		x := o.X
+		switch spaceX := o.Width - measure(line); t.HAlign {
+		case "center":
+			x += spaceX / 2
+		case "right":
+			x += spaceX
+		}
+
+		txt.Dot = pixel.V(x, top-atlas.Ascent()-float64(i)*atlas.LineHeight())
+		if _, err := txt.WriteString(line); err != nil {
+			log.WithError(err).Error("Object.DrawText: could not write text")
+			return err
+		}
+	}
+
+	txt.Draw(target, mat)
+	return nil
+}
+
+// defaultText returns the text attributes used by Tiled when they are omitted.
+func defaultText() Text {
+	return Text{
+		FontFamily: "sans-serif",
+		PixelSize:  16,
+		Kerning:    true,
+		HAlign:     "left",
+		VAlign:     "top",
+	}
+}
+
+// wrapText will split the line into lines which fit within the width, breaking between words.  Words which are wider
+// than the width are placed on a line of their own.
+func wrapText(line string, width float64, measure func(string) float64) []string {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	current := words[0]
+	for _, word := range words[1:] {
+		if next := current + " " + word; measure(next) <= width {
+			current = next
+			continue
+		}
+		lines = append(lines, current)
+		current = word
+	}
+
+	return append(lines, current)
+}
@@ -0,0 +1,42 @@
+package tilepix
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestText_String(t *testing.T) {
+	tx := &Text{Text: "Hello", FontFamily: "sans-serif", PixelSize: 16}
+
+	want := "Text{Text: 'Hello', FontFamily: sans-serif, PixelSize: 16}"
+	if got := tx.String(); got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
+
+func Test_wrapText(t *testing.T) {
+	// Each character is measured as one unit wide.
+	measure := func(s string) float64 {
+		return float64(utf8.RuneCountInString(s))
+	}
+
+	tests := []struct {
+		name  string
+		line  string
+		width float64
+		want  []string
+	}{
+		{name: "fits", line: "hello world", width: 11, want: []string{"hello world"}},
+		{name: "wraps", line: "hello big world", width: 9, want: []string{"hello big", "world"}},
+		{name: "long word", line: "a wonderful day", width: 5, want: []string{"a", "wonderful", "day"}},
+		{name: "empty", line: "", width: 5, want: []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapText(tt.line, tt.width, measure); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrapText() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,13 @@ package tilepix
 
 import (
 	"fmt"
+	"image/color"
 	"math"
+	"time"
 
 	"github.com/faiface/pixel"
+
+	log "github.com/sirupsen/logrus"
 )
 
 /*
@@ -16,22 +20,74 @@ import (
 
 // Tile is a TMX file structure which holds a Tiled tile.
 type Tile struct {
-	ID    ID     `xml:"id,attr"`
-	Image *Image `xml:"image"`
+	ID         ID          `xml:"id,attr"`
+	Properties []*Property `xml:"properties>property"`
+	Image      *Image      `xml:"image"`
 	// ObjectGroup is set if objects have been added to individual sprites in Tiled.
 	ObjectGroup *ObjectGroup `xml:"objectgroup,omitempty"`
+	// Animation is set if the tile is animated in Tiled.  The frames are played in order, and loop.
+	Animation []*Frame `xml:"animation>frame"`
 
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
 
+// Frame is a TMX file structure which holds a single frame of a tiles' animation.
+type Frame struct {
+	// TileID is the ID of the tile, within the same tileset, to display for this frame.
+	TileID ID `xml:"tileid,attr"`
+	// Duration is how long the frame is displayed for, in milliseconds.
+	Duration int `xml:"duration,attr"`
+}
+
+func (f *Frame) String() string {
+	return fmt.Sprintf("Frame{TileID: %d, Duration: %dms}", f.TileID, f.Duration)
+}
+
+// GetProperty returns the Tiles' Property by its name, or nil if there is no such property.
+func (t *Tile) GetProperty(name string) *Property {
+	return getProperty(t.Properties, name)
+}
+
 func (t *Tile) String() string {
 	return fmt.Sprintf("Tile{ID: %d}", t.ID)
 }
 
+// IsAnimated returns whether the tile has an animation.
+func (t *Tile) IsAnimated() bool {
+	return len(t.Animation) > 0
+}
+
+// frameAt returns the ID of the tile to display after the animation has been playing for the elapsed time.  The ID of
+// the tile itself is returned if it is not animated.
+func (t *Tile) frameAt(elapsed time.Duration) ID {
+	var total time.Duration
+	for _, f := range t.Animation {
+		total += time.Duration(f.Duration) * time.Millisecond
+	}
+	if total <= 0 {
+		return t.ID
+	}
+
+	elapsed %= total
+	for _, f := range t.Animation {
+		d := time.Duration(f.Duration) * time.Millisecond
+		if elapsed < d {
+			return f.TileID
+		}
+		elapsed -= d
+	}
+
+	return t.ID
+}
+
 func (t *Tile) setParent(m *Map) {
 	t.parentMap = m
 
+	for _, p := range t.Properties {
+		p.setParent(m)
+	}
+
 	if t.Image != nil {
 		t.Image.setParent(m)
 	}
@@ -48,6 +104,11 @@ type DecodedTile struct {
 
 	sprite    *pixel.Sprite
 	transform pixel.Matrix
+	// spriteID is the ID of the tile the sprite was framed from.  This differs from ID for animated tiles.
+	spriteID ID
+	// tile holds the tileset data for the tile, such as its' animation.  This is nil if the tileset does not define
+	// anything for the tile.
+	tile *Tile
 
 	// parentMap is the map which contains this object
 	parentMap *Map
@@ -56,12 +117,35 @@ type DecodedTile struct {
 // Draw will draw the tile to the target provided.  This will calculate the sprite from the provided tileset and set the
 // DecodedTiles' internal `sprite` property; this is so it is only calculated the first time.
 func (t *DecodedTile) Draw(ind, columns, numRows int, ts *Tileset, target pixel.Target, offset pixel.Vec) {
-	if t.IsNil() {
+	t.draw(ind, columns, numRows, ts, target, offset, nil)
+}
+
+// draw will draw the tile to the target, with the colour mask applied.  A nil mask draws the tile unchanged.
+func (t *DecodedTile) draw(ind, columns, numRows int, ts *Tileset, target pixel.Target, offset pixel.Vec, mask color.Color) {
+	if !t.prepare(ind, columns, numRows, ts) {
 		return
 	}
+	t.sprite.DrawColorMask(target, t.transform.Moved(offset), mask)
+}
+
+// prepare will set the tiles' sprite, and the transform to draw it with, if they have not already been set for the
+// current frame of the tile.  This returns false if the tile should not be drawn.
+func (t *DecodedTile) prepare(ind, columns, numRows int, ts *Tileset) bool {
+	if t.IsNil() {
+		return false
+	}
+
+	if t.sprite != nil && t.spriteID != t.CurrentID() {
+		// The animation has moved on to a different frame.
+		t.sprite = nil
+	}
 
 	if t.sprite == nil {
 		t.setSprite(columns, numRows, ts)
+		if t.sprite == nil {
+			// The tiles' image could not be loaded, this has already been logged.
+			return false
+		}
 
 		// Calculate the framing for the tile within its tileset's source image
 		pos := t.Position(ind, ts)
@@ -78,13 +162,83 @@ func (t *DecodedTile) Draw(ind, columns, numRows int, ts *Tileset, target pixel.
 		}
 		t.transform = transform
 	}
-	t.sprite.Draw(target, t.transform.Moved(offset))
+	return true
+}
+
+// CurrentID returns the ID of the tile to display.  For animated tiles this is the tile of the current animation frame,
+// according to the time passed to `Map.Update`; for all other tiles this is the same as ID.
+func (t *DecodedTile) CurrentID() ID {
+	if !t.IsAnimated() || t.parentMap == nil {
+		return t.ID
+	}
+
+	return t.tile.frameAt(t.parentMap.elapsed)
 }
 
-// Position returns the relative game position.
+// IsAnimated returns whether the tile has an animation.
+func (t *DecodedTile) IsAnimated() bool {
+	return t.tile != nil && t.tile.IsAnimated()
+}
+
+// Position returns the relative game position.  For infinite maps this may be negative.
+//
+// Tiles from a collection of images may each be a different size; as in Tiled, these are anchored to the bottom-left
+// of their cell in the map.  For isometric maps, tiles are anchored to the bottom corner of their diamond; for staggered
+// and hexagonal maps, tiles are anchored to the bottom-left of their bounding box.  The tile offset of the tileset is
+// then applied.
 func (t DecodedTile) Position(ind int, ts *Tileset) pixel.Vec {
-	gamePos := indexToGamePos(ind, t.parentMap.Width, t.parentMap.Height)
-	return gamePos.ScaledXY(pixel.V(float64(ts.TileWidth), float64(ts.TileHeight))).Add(pixel.V(float64(ts.TileWidth), float64(ts.TileHeight)).Scaled(0.5))
+	return t.cellPosition(ind, ts).Add(ts.drawOffset())
+}
+
+// bounds returns the area, in game co-ordinates, the tile is drawn to.  This is relative to the layer, without the
+// layers' offset.
+func (t DecodedTile) bounds(ind int, ts *Tileset) pixel.Rect {
+	size := ts.tileSize(t.CurrentID())
+	if t.DiagonalFlip {
+		size = pixel.V(size.Y, size.X)
+	}
+	return pixel.Rect{Max: size}.Moved(t.Position(ind, ts).Sub(size.Scaled(0.5)))
+}
+
+// extent returns the area, in game co-ordinates, the tile may be drawn to; the union of its' bounds in each frame of its'
+// animation.  Only the tiles of collections of images change size between frames.
+func (t DecodedTile) extent(ind int, ts *Tileset) pixel.Rect {
+	b := t.bounds(ind, ts)
+	if !t.IsAnimated() || !ts.IsCollection() {
+		return b
+	}
+
+	for _, f := range t.tile.Animation {
+		frame := t
+		frame.ID, frame.tile = f.TileID, nil
+		b = b.Union(frame.bounds(ind, ts))
+	}
+	return b
+}
+
+// cellPosition returns the relative game position of the tile, anchored to its' cell in the map.
+func (t DecodedTile) cellPosition(ind int, ts *Tileset) pixel.Vec {
+	size := ts.tileSize(t.CurrentID())
+
+	if m := t.parentMap; m.isIsometric() || m.isStaggered() {
+		tile := pixel.V(float64(ind%m.Width+m.StartX), float64(ind/m.Width+m.StartY))
+
+		var bottomLeft pixel.Vec
+		if m.isStaggered() {
+			bottomLeft = m.TileToWorld(tile).Sub(pixel.V(0, m.staggerParams().tileHeight))
+		} else {
+			bottomLeft = m.TileToWorld(tile.Add(pixel.V(1, 1))).Sub(pixel.V(float64(m.TileWidth)/2, 0))
+		}
+		return bottomLeft.Add(size.Scaled(0.5))
+	}
+
+	gamePos := indexToGamePos(ind, t.parentMap.Width, t.parentMap.Height).Add(t.parentMap.tileOrigin())
+	if ts.IsCollection() {
+		cellSize := pixel.V(float64(t.parentMap.TileWidth), float64(t.parentMap.TileHeight))
+		return gamePos.ScaledXY(cellSize).Add(size.Scaled(0.5))
+	}
+
+	return gamePos.ScaledXY(size).Add(size.Scaled(0.5))
 }
 
 func (t *DecodedTile) String() string {
@@ -97,6 +251,26 @@ func (t *DecodedTile) IsNil() bool {
 	return t.Nil
 }
 
+// gid returns the global tile ID of this tile, including the flip flags.
+func (t *DecodedTile) gid() GID {
+	if t.IsNil() {
+		return 0
+	}
+
+	gid := t.Tileset.FirstGID + GID(t.ID)
+	if t.HorizontalFlip {
+		gid |= gidHorizontalFlip
+	}
+	if t.VerticalFlip {
+		gid |= gidVerticalFlip
+	}
+	if t.DiagonalFlip {
+		gid |= gidDiagonalFlip
+	}
+
+	return gid
+}
+
 func (t *DecodedTile) setParent(m *Map) {
 	t.parentMap = m
 }
@@ -107,8 +281,22 @@ func (t *DecodedTile) setSprite(columns, numRows int, ts *Tileset) {
 	}
 
 	if t.sprite == nil {
+		t.spriteID = t.CurrentID()
+
+		if ts.IsCollection() {
+			// The tile is drawn from its' own image.
+			pic, err := ts.tilePicture(t.spriteID)
+			if err != nil {
+				log.WithError(err).WithField("ID", t.spriteID).Error("DecodedTile.setSprite: could not get tile picture")
+				return
+			}
+
+			t.sprite = pixel.NewSprite(pic, pic.Bounds())
+			return
+		}
+
 		// Calculate the framing for the tile within its tileset's source image
-		x, y := tileIDToCoord(t.ID, columns, numRows)
+		x, y := tileIDToCoord(t.spriteID, columns, numRows)
 		iX := float64(x)*float64(ts.TileWidth) + float64(ts.Margin+ts.Spacing*(x-1))
 		fX := iX + float64(ts.TileWidth)
 		iY := float64(y)*float64(ts.TileHeight) + float64(ts.Margin+ts.Spacing*(y-1))
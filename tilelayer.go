@@ -1,8 +1,11 @@
 package tilepix
 
 import (
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"image"
+	"math"
 
 	"github.com/faiface/pixel"
 
@@ -19,93 +22,260 @@ import (
 
 // TileLayer is a TMX file structure which can hold any type of Tiled layer.
 type TileLayer struct {
-	Name       string      `xml:"name,attr"`
-	Opacity    float32     `xml:"opacity,attr"`
-	OffSetX    float64     `xml:"offsetx,attr"`
-	OffSetY    float64     `xml:"offsety,attr"`
-	Visible    bool        `xml:"visible,attr"`
+	Name    string  `xml:"name,attr"`
+	Opacity float32 `xml:"opacity,attr"`
+	OffSetX float64 `xml:"offsetx,attr"`
+	OffSetY float64 `xml:"offsety,attr"`
+	Visible bool    `xml:"visible,attr"`
+	// TintColor is the Tiled colour string the layers' tiles are multiplied by, empty if the layer is not tinted.
+	TintColor string `xml:"tintcolor,attr"`
+	// ParallaxX and ParallaxY are the factors the layer scrolls by, relative to the camera.  A factor of 1 scrolls with
+	// the map, lower factors scroll slower so the layer appears further away.
+	ParallaxX  float64     `xml:"parallaxx,attr"`
+	ParallaxY  float64     `xml:"parallaxy,attr"`
 	Properties []*Property `xml:"properties>property"`
 	Data       Data        `xml:"data"`
 	// DecodedTiles is the attribute you should use instead of `Data`.
-	// Tile entry at (x,y) is obtained using l.DecodedTiles[y*map.Width+x].
+	// Tile entry at (x,y) is obtained using l.DecodedTiles[y*map.Width+x].  For infinite maps, (x,y) is relative to
+	// the maps' `StartX` and `StartY`; the layers' chunks are available from `Data.Chunks`.
 	DecodedTiles []*DecodedTile
-	// Tileset is only set when the layer uses a single tileset and NilLayer is false.
+	// Tileset is only set when the layer uses a single tileset and NilLayer is false.  Layers may use tiles from
+	// several tilesets; the tileset of each tile is held in its' DecodedTile.
 	Tileset *Tileset
 	// Empty should be set when all entries of the layer are NilTile.
 	Empty bool
 
-	batch   *pixel.Batch
-	isDirty bool
-	static  bool
+	// chunks holds the batches of the layer, which is split into square chunks of cells; see `TileLayer.arrange`.
+	chunks []*batchChunk
+	// chunkColumns is the number of chunks across the layer.
+	chunkColumns int
+	// deferred holds the groups of tiles which are drawn after every chunk, in draw order; see `TileLayer.arrange`.
+	deferred []*batchGroup
+	// arranged is whether the tiles have been split between the chunks and deferred groups since the layer was set dirty.
+	arranged bool
+	// batch is the batch returned by `TileLayer.Batch`.
+	batch  *pixel.Batch
+	static bool
+	// animatedTiles holds the indices of the tiles in the layer which have an animation.
+	animatedTiles []int
+	// group is the group which contains this layer, nil if it is at the top level of the map.
+	group *Group
 
 	// parentMap is the map which contains this object
 	parentMap *Map
 }
 
-// Batch returns the batch with the picture data from the tileset associated with this layer.
-func (l *TileLayer) Batch() (*pixel.Batch, error) {
-	if l.batch == nil {
-		log.Debug("TileLayer.Batch: batch not initialised, creating")
+// batchChunkSize is the width and height, in cells, of the chunks tile layers are batched in.
+const batchChunkSize = 32
+
+// maxChunkOverlaps is the number of deferred tiles overlapping a chunk which are checked against its' tiles when a layer
+// is arranged.  Once more overlap the chunk, the rest of its' tiles are deferred; this keeps arranging layers of large
+// tiles quick.
+const maxChunkOverlaps = 64
+
+// batchChunk holds the batches of the tiles in a chunk of a tile layer.
+type batchChunk struct {
+	// cells is the area of the layer in the chunk, as columns and rows of the layers' DecodedTiles.
+	cells image.Rectangle
+	// batchGroup holds the tiles of the chunk which are drawn with the chunk.
+	batchGroup
+	// deferred holds the groups of the chunks' tiles which are drawn after every chunk.
+	deferred []*batchGroup
+	isDirty  bool
+}
 
-		if l.Tileset == nil {
-			err := errors.New("cannot create sprite from nil tileset")
-			log.WithError(err).Error("TileLayer.Batch: layers' tileset is nil")
-			return nil, err
-		}
+// batchGroup holds tiles of a chunk which are drawn together, in draw order.
+type batchGroup struct {
+	// chunk is the chunk the tiles are in.
+	chunk *batchChunk
+	// tiles holds the indices of the tiles in the layers' DecodedTiles.
+	tiles []int
+	// batches holds the tiles in a batch for each run of consecutive tiles from the same tileset.
+	batches []*layerBatch
+	// bounds is the area the tiles are drawn to, relative to the layer without its' offset.
+	bounds pixel.Rect
+}
+
+// layerBatch holds a run of consecutive tiles, in draw order, from a single tileset.  The tiles are drawn to the batch,
+// unless the tileset is a collection of images; these cannot be batched, so batch is nil and the tiles are drawn
+// individually.
+type layerBatch struct {
+	tileset *Tileset
+	batch   *pixel.Batch
+	// tiles holds the indices of the tiles in the layers' DecodedTiles.
+	tiles []int
+}
 
-		pictureData := l.Tileset.setSprite()
-		l.batch = pixel.NewBatch(&pixel.TrianglesData{}, pictureData)
+// Batch returns the batch with the picture data from the tileset associated with this layer.  This is only available for
+// layers which use a single tileset.
+//
+// Deprecated: `TileLayer.Draw` no longer draws the layer with this batch, as it batches the tiles in chunks of the layer;
+// so the batch does not hold the tiles of the layer once it has been drawn, or after `Map.Update`.  Use `TileLayer.Draw`
+// or `TileLayer.DrawRect` to draw the layer.
+func (l *TileLayer) Batch() (*pixel.Batch, error) {
+	if l.Tileset == nil {
+		err := errors.New("cannot create sprite from nil tileset")
+		log.WithError(err).Error("TileLayer.Batch: layers' tileset is nil")
+		return nil, err
+	}
+	if l.Tileset.IsCollection() {
+		err := errors.New("cannot create batch for a collection of images")
+		log.WithError(err).Error("TileLayer.Batch: layers' tileset has no single image")
+		return nil, err
 	}
 
-	l.batch.Clear()
+	if l.batch == nil {
+		log.Debug("TileLayer.Batch: batch not initialised, creating")
+		l.batch = pixel.NewBatch(&pixel.TrianglesData{}, l.Tileset.setSprite())
+	}
+	b := l.batch
+	b.Clear()
 
-	return l.batch, nil
+	return b, nil
 }
 
-// Draw will use the TileLayers' batch to draw all tiles within the TileLayer to the target.
+// Draw will use the TileLayers' batches to draw all tiles within the TileLayer to the target.  Tiles overlap the tiles
+// drawn before them in the maps' render order, whichever tileset or chunk they are from.  The layer is split into chunks
+// of cells, which are batched separately; tiles which overlap tiles in other chunks are drawn after the chunks, in the
+// render order, so the order is kept; see `TileLayer.arrange`.  Each run of consecutive tiles from the same tileset is
+// drawn with a batch.  Tilesets which are a collection of images cannot be batched, so those tiles are drawn to the
+// target individually, in their place in the order.
+//
+// Only the chunks which are dirty are re-batched; see `TileLayer.SetDirty`.  After changing some of the layers'
+// DecodedTiles, use `TileLayer.SetTileDirty` for each of them, so only the chunks holding those tiles are re-batched.
 func (l *TileLayer) Draw(target pixel.Target) error {
-	// Only draw if the layer is dirty.
-	if l.isDirty {
-		// Initialise the batch
-		if _, err := l.Batch(); err != nil {
-			log.WithError(err).Error("TileLayer.Draw: could not get batch")
-			return err
-		}
+	return l.draw(target, nil)
+}
 
-		ts := l.Tileset
-		numRows := ts.Tilecount / ts.Columns
+// DrawRect will draw the tiles within the TileLayer which intersect rect to the target, in the same way as
+// `TileLayer.Draw`.  rect is in game co-ordinates, including the offset of the layer.  Only the chunks of the layer
+// which intersect rect are re-batched, if they are dirty, and drawn; so drawing a small part of a large layer is cheap.
+// rect only selects which tiles are drawn, so changing it does not cause a static layer to be re-batched.
+func (l *TileLayer) DrawRect(target pixel.Target, rect pixel.Rect) error {
+	return l.draw(target, &rect)
+}
+
+// draw will draw the chunks of the layer within rect to the target, or all of the chunks if rect is nil.
+func (l *TileLayer) draw(target pixel.Target, rect *pixel.Rect) error {
+	if !l.arranged {
+		l.arrange()
+	}
+
+	// The tiles are positioned relative to the layer, without its' offset.
+	var r pixel.Rect
+	if rect != nil {
+		r = rect.Moved(l.TotalOffset().Scaled(-1))
+	}
+	visible := func(g *batchGroup) bool {
+		return rect == nil || rectsOverlap(g.bounds, r)
+	}
 
-		// Loop through each decoded tile
-		for tileIndex, tile := range l.DecodedTiles {
-			// The Y component of the offset is set in Tiled from top down, setting here to negative because we want
-			// from the bottom up.
-			layerOffset := pixel.V(l.OffSetX, -l.OffSetY)
-			tile.Draw(tileIndex, ts.Columns, numRows, ts, l.batch, layerOffset)
+	for _, c := range l.chunksWithin(rect) {
+		// Only re-batch the chunk if it is dirty.
+		if c.isDirty {
+			c.rebatch(l)
 		}
 
-		// Batch is drawn to, layer is no longer dirty.
-		l.SetDirty(false)
+		if visible(&c.batchGroup) {
+			c.draw(l, target)
+		}
 	}
 
-	l.batch.Draw(target)
+	// The chunks which are still dirty are not within rect, so none of their tiles are drawn.
+	for _, g := range l.deferred {
+		if !g.chunk.isDirty && visible(g) {
+			g.draw(l, target)
+		}
+	}
 
-	// Reset the dirty flag if the layer is not static
+	// Reset the dirty flag if the layer is not static.  The tiles stay arranged, so later calls only re-batch the chunks
+	// they draw.
 	if !l.static {
-		l.SetDirty(true)
+		for _, c := range l.chunks {
+			c.isDirty = true
+		}
 	}
 
 	return nil
 }
 
-// SetDirty will update the TileLayers' `dirty` property.  If true, this will cause the TileLayers' batch be cleared and
-// re-drawn next time `TileLayer.Draw` is called.
+// GetName returns the name of the layer.
+func (l *TileLayer) GetName() string {
+	return l.Name
+}
+
+// GetProperty returns the TileLayers' Property by its name, or nil if there is no such property.  Properties are
+// inherited from the groups which contain this layer.
+func (l *TileLayer) GetProperty(name string) *Property {
+	if p := getProperty(l.Properties, name); p != nil {
+		return p
+	}
+	return l.group.GetProperty(name)
+}
+
+// IsAnimated returns whether the layer contains any animated tiles.
+func (l *TileLayer) IsAnimated() bool {
+	return len(l.animatedTiles) > 0
+}
+
+// IsVisible returns whether the layer is visible.  A layer is only visible if all of the groups which contain it are
+// visible.
+func (l *TileLayer) IsVisible() bool {
+	return l.Visible && l.group.IsVisible()
+}
+
+// Parent returns the group which contains this layer, or nil if it is at the top level of the map.
+func (l *TileLayer) Parent() *Group {
+	return l.group
+}
+
+// SetDirty will update the `dirty` property of each of the TileLayers' chunks.  If true, this will cause the batches of
+// each chunk to be cleared and re-drawn the next time the chunk is drawn, and the tiles to be arranged between the
+// chunks again; this should be done after changing many of the layers' DecodedTiles, see `TileLayer.SetTileDirty` to
+// change a few.  Chunks are dirty until they are first drawn.
 func (l *TileLayer) SetDirty(newVal bool) {
 	log.WithField("Dirty", newVal).Trace("TileLayer.SetDirty: setting dirty property")
-	l.isDirty = newVal
+	for _, c := range l.chunks {
+		c.isDirty = newVal
+	}
+	if newVal {
+		l.arranged = false
+	}
+}
+
+// SetTileDirty will set the chunk holding the tile at the index of the layers' DecodedTiles dirty, so that only that chunk
+// is re-batched the next time it is drawn.  This should be done after changing the tile, instead of setting the whole
+// layer dirty with `TileLayer.SetDirty`.  If the new tile is drawn outside of its' cell, the tiles are arranged between
+// the chunks again, which sets every chunk dirty.
+func (l *TileLayer) SetTileDirty(tileIndex int) {
+	log.WithField("Index", tileIndex).Trace("TileLayer.SetTileDirty: setting chunk dirty")
+
+	m := l.parentMap
+	if t := l.DecodedTiles[tileIndex]; !t.IsNil() && !m.isIsometric() && !m.isStaggered() {
+		// The tile was arranged by the area of its' cell, or of the tile drawn there before.
+		cell := m.cellBounds(tileIndex)
+		if extent := t.extent(tileIndex, t.Tileset); extent.Intersect(cell) != extent {
+			l.SetDirty(true)
+			return
+		}
+	}
+
+	l.chunkAt(tileIndex).isDirty = true
+}
+
+// isDirty returns whether any of the chunks of the layer are dirty.
+func (l *TileLayer) isDirty() bool {
+	for _, c := range l.chunks {
+		if c.isDirty {
+			return true
+		}
+	}
+	return false
 }
 
-// SetStatic will update the TileLayers' `static` property.  If false, this will set the dirty property to true each
-// time after `TileLayer.Draw` is called, so that the layer is drawn everytime.
+// SetStatic will update the TileLayers' `static` property.  If false, this will set the dirty property of each chunk to
+// true each time after `TileLayer.Draw` is called, so that the layer is drawn everytime.  The tiles are not arranged
+// between the chunks again; `TileLayer.SetDirty` should still be used after changing tiles to tiles of a different size.
 func (l *TileLayer) SetStatic(newVal bool) {
 	log.WithField("Static", newVal).Debug("TileLayer.SetStatic: setting static property")
 	l.static = newVal
@@ -115,6 +285,322 @@ func (l *TileLayer) String() string {
 	return fmt.Sprintf("TileLayer{Name: '%s', Properties: %v, TileCount: %d}", l.Name, l.Properties, len(l.DecodedTiles))
 }
 
+// TotalOffset returns the offset of the layer, combined with the offsets of the groups which contain it.  The offset is
+// in game co-ordinates; the Y component is set in Tiled from top down, so is negated here because we want from the
+// bottom up.
+func (l *TileLayer) TotalOffset() pixel.Vec {
+	return pixel.V(l.OffSetX, -l.OffSetY).Add(l.group.TotalOffset())
+}
+
+// TotalOpacity returns the opacity of the layer, multiplied by the opacity of the groups which contain it.
+func (l *TileLayer) TotalOpacity() float64 {
+	return float64(l.Opacity) * l.group.TotalOpacity()
+}
+
+// TotalParallax returns the parallax factors of the layer, multiplied by the parallax factors of the groups which
+// contain it.
+func (l *TileLayer) TotalParallax() pixel.Vec {
+	return pixel.V(l.ParallaxX, l.ParallaxY).ScaledXY(l.group.TotalParallax())
+}
+
+// TotalTint returns the tint colour of the layer, multiplied by the tint colours of the groups which contain it.
+func (l *TileLayer) TotalTint() pixel.RGBA {
+	return tintColor(l.TintColor).Mul(l.group.TotalTint())
+}
+
+// UnmarshalXML decodes a tile layer.  Tiled omits the opacity, visible and parallax attributes when they are the
+// default values, so these are set before decoding.
+func (l *TileLayer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type tileLayer TileLayer
+	raw := tileLayer{Opacity: 1, Visible: true, ParallaxX: 1, ParallaxY: 1}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		log.WithError(err).Error("TileLayer.UnmarshalXML: could not decode layer")
+		return err
+	}
+
+	*l = TileLayer(raw)
+	return nil
+}
+
+// colorMask returns the colour the layers' tiles are drawn with; the tint of the layer, faded by its' opacity.
+func (l *TileLayer) colorMask() pixel.RGBA {
+	return l.TotalTint().Scaled(l.TotalOpacity())
+}
+
+// getChunks returns the chunks of the layer, creating them if the layer has not been drawn before, or the size of the
+// map has changed.  Chunks are ordered row by row from the top-left of the layer.
+func (l *TileLayer) getChunks() []*batchChunk {
+	m := l.parentMap
+	columns, rows := (m.Width+batchChunkSize-1)/batchChunkSize, (m.Height+batchChunkSize-1)/batchChunkSize
+	if l.chunks != nil && len(l.chunks) == columns*rows {
+		return l.chunks
+	}
+
+	log.WithFields(log.Fields{"Columns": columns, "Rows": rows}).Debug("TileLayer.getChunks: chunks not initialised, creating")
+
+	l.chunks = make([]*batchChunk, 0, columns*rows)
+	l.chunkColumns = columns
+	l.arranged = false
+	for y := 0; y < rows; y++ {
+		for x := 0; x < columns; x++ {
+			cells := image.Rect(x*batchChunkSize, y*batchChunkSize, (x+1)*batchChunkSize, (y+1)*batchChunkSize)
+			c := &batchChunk{cells: cells.Intersect(image.Rect(0, 0, m.Width, m.Height)), isDirty: true}
+			c.chunk = c
+			l.chunks = append(l.chunks, c)
+		}
+	}
+	return l.chunks
+}
+
+// chunkAt returns the chunk holding the tile at the index of the layers' DecodedTiles.
+func (l *TileLayer) chunkAt(tileIndex int) *batchChunk {
+	chunks := l.getChunks()
+	x, y := tileIndex%l.parentMap.Width, tileIndex/l.parentMap.Width
+	return chunks[y/batchChunkSize*l.chunkColumns+x/batchChunkSize]
+}
+
+// chunksWithin returns the chunks of the layer which may hold tiles intersecting rect, in the order they should be drawn.
+// All of the chunks are returned if rect is nil.
+func (l *TileLayer) chunksWithin(rect *pixel.Rect) []*batchChunk {
+	m := l.parentMap
+	chunks := l.getChunks()
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	cells := image.Rect(0, 0, m.Width, m.Height)
+	if rect != nil {
+		// The tiles are positioned relative to the layer, without its' offset.
+		cells = m.cellsWithin(rect.Moved(l.TotalOffset().Scaled(-1)))
+	}
+
+	// The chunks are drawn in the same order as the tiles within them.
+	upward, leftward := m.renderDirection()
+	rows := len(chunks) / l.chunkColumns
+
+	var within []*batchChunk
+	for row := 0; row < rows; row++ {
+		y := row
+		if upward {
+			y = rows - row - 1
+		}
+		for col := 0; col < l.chunkColumns; col++ {
+			x := col
+			if leftward {
+				x = l.chunkColumns - col - 1
+			}
+			if c := chunks[y*l.chunkColumns+x]; c.cells.Overlaps(cells) {
+				within = append(within, c)
+			}
+		}
+	}
+	return within
+}
+
+// arrange will split the tiles of the layer between the chunks they are in.  Chunks are drawn one at a time, which only
+// draws the tiles in the maps' render order if tiles do not overlap tiles of other chunks.  So the tiles of each chunk
+// which are drawn outside of the chunks' cells, and the tiles which overlap those drawn before them, are deferred; the
+// deferred tiles are drawn after every chunk, in the render order.  As the chunks of orthogonal maps do not overlap, this
+// keeps the render order wherever tiles overlap.  The tiles of isometric, staggered and hexagonal maps overlap the tiles
+// in the cells around them, so are all deferred.
+func (l *TileLayer) arrange() {
+	m := l.parentMap
+	chunks := l.getChunks()
+	for _, c := range chunks {
+		c.tiles = c.tiles[:0]
+		c.deferred = nil
+		c.isDirty = true
+	}
+	l.deferred = nil
+
+	// overlaps holds the area of each deferred tile which overlaps each chunk, so tiles drawn after them can be deferred
+	// as well.
+	overlaps := make(map[*batchChunk][]pixel.Rect)
+	var last *batchGroup
+	for _, tileIndex := range m.drawOrder() {
+		// Nil tiles are arranged as tiles filling their cell, so a tile can be set in their place without arranging the
+		// layer again.
+		c := l.chunkAt(tileIndex)
+		extent := m.cellBounds(tileIndex)
+		if t := l.DecodedTiles[tileIndex]; !t.IsNil() {
+			extent = t.extent(tileIndex, t.Tileset)
+		}
+		if l.withinChunk(c, extent) && len(overlaps[c]) < maxChunkOverlaps && !overlapsAny(overlaps[c], extent) {
+			c.tiles = append(c.tiles, tileIndex)
+			continue
+		}
+
+		if !m.isIsometric() && !m.isStaggered() {
+			for _, o := range l.chunksOverlapping(extent) {
+				overlaps[o] = append(overlaps[o], extent)
+			}
+		}
+		if last == nil || last.chunk != c {
+			last = &batchGroup{chunk: c}
+			l.deferred = append(l.deferred, last)
+			c.deferred = append(c.deferred, last)
+		}
+		last.tiles = append(last.tiles, tileIndex)
+	}
+
+	log.WithFields(log.Fields{"Layer": l.Name, "Deferred groups": len(l.deferred)}).Debug("TileLayer.arrange: arranged tiles")
+	l.arranged = true
+}
+
+// withinChunk returns whether the area, relative to the layer, is within the cells of the chunk.  The chunks at the
+// edges of the layer extend outwards without limit.  This is always false for isometric, staggered and hexagonal maps,
+// as their chunks do not cover rectangular areas.
+func (l *TileLayer) withinChunk(c *batchChunk, area pixel.Rect) bool {
+	if m := l.parentMap; m.isIsometric() || m.isStaggered() {
+		return false
+	}
+
+	chunks := l.chunksOverlapping(area)
+	return len(chunks) == 1 && chunks[0] == c
+}
+
+// chunksOverlapping returns the chunks of an orthogonal layer whose cells overlap the area, relative to the layer.  The
+// chunks at the edges of the layer extend outwards without limit.
+func (l *TileLayer) chunksOverlapping(area pixel.Rect) []*batchChunk {
+	m := l.parentMap
+	chunks := l.getChunks()
+	if len(chunks) == 0 {
+		return nil
+	}
+	rows := len(chunks) / l.chunkColumns
+
+	// The position of the area in cells; columns from the left and rows from the top of the layer.
+	origin := m.tileOrigin()
+	tw, th := float64(m.TileWidth), float64(m.TileHeight)
+	minX, maxX := area.Min.X/tw-origin.X, area.Max.X/tw-origin.X
+	minY, maxY := float64(m.Height)+origin.Y-area.Max.Y/th, float64(m.Height)+origin.Y-area.Min.Y/th
+
+	// An area which ends on the edge of a chunk does not overlap the next one.
+	chunkRange := func(min, max float64, n int) (int, int) {
+		first := int(math.Floor(min / batchChunkSize))
+		last := int(math.Ceil(max/batchChunkSize)) - 1
+		return clampInt(first, 0, n-1), clampInt(last, 0, n-1)
+	}
+	x0, x1 := chunkRange(minX, maxX, l.chunkColumns)
+	y0, y1 := chunkRange(minY, maxY, rows)
+
+	var overlapping []*batchChunk
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			overlapping = append(overlapping, chunks[y*l.chunkColumns+x])
+		}
+	}
+	return overlapping
+}
+
+// rebatch will clear the batches of the chunk, and draw the tiles of the layer within the chunk to them.
+func (c *batchChunk) rebatch(l *TileLayer) {
+	// The colour mask applies to all tiles drawn to the batches.
+	mask := l.colorMask()
+
+	c.batchGroup.rebatch(l, mask)
+	for _, g := range c.deferred {
+		g.rebatch(l, mask)
+	}
+
+	// Batches are drawn to, chunk is no longer dirty.
+	c.isDirty = false
+}
+
+// rebatch will clear the batches of the group, and draw its' tiles to them with the colour mask.
+func (g *batchGroup) rebatch(l *TileLayer, mask pixel.RGBA) {
+	previous := g.batches
+	g.batches = nil
+	g.bounds = pixel.Rect{}
+
+	// Loop through each decoded tile in the maps' render order, so taller tiles overlap those drawn before them.  A new
+	// batch is started whenever the tileset changes, so the order is kept between tilesets.
+	var lb *layerBatch
+	for _, tileIndex := range g.tiles {
+		t := l.DecodedTiles[tileIndex]
+		if t.IsNil() {
+			continue
+		}
+
+		ts := t.Tileset
+		if bounds := t.bounds(tileIndex, ts); g.bounds.Area() == 0 {
+			g.bounds = bounds
+		} else {
+			g.bounds = g.bounds.Union(bounds)
+		}
+
+		if lb == nil || lb.tileset != ts {
+			lb = g.nextBatch(previous, ts, mask)
+		}
+		lb.tiles = append(lb.tiles, tileIndex)
+		if lb.batch != nil {
+			t.Draw(tileIndex, ts.Columns, ts.numRows(), ts, lb.batch, l.TotalOffset())
+		}
+	}
+}
+
+// nextBatch adds a batch for the tileset to the end of the groups' batches.  The batch at the same position in previous,
+// the batches of the group before it was re-batched, is cleared and re-used if it is for the same tileset.
+func (g *batchGroup) nextBatch(previous []*layerBatch, ts *Tileset, mask pixel.RGBA) *layerBatch {
+	if i := len(g.batches); i < len(previous) && previous[i].tileset == ts {
+		lb := previous[i]
+		lb.tiles = lb.tiles[:0]
+		if lb.batch != nil {
+			lb.batch.Clear()
+			lb.batch.SetColorMask(mask)
+		}
+		g.batches = append(g.batches, lb)
+		return lb
+	}
+
+	lb := &layerBatch{tileset: ts}
+	if !ts.IsCollection() {
+		log.WithField("Tileset", ts.Name).Debug("batchGroup.nextBatch: batch not initialised, creating")
+
+		lb.batch = pixel.NewBatch(&pixel.TrianglesData{}, ts.setSprite())
+		lb.batch.SetColorMask(mask)
+	}
+	g.batches = append(g.batches, lb)
+	return lb
+}
+
+// draw will draw the batches of the group to the target in order, with the tiles from collections of images drawn
+// individually.
+func (g *batchGroup) draw(l *TileLayer, target pixel.Target) {
+	mask := l.colorMask()
+	for _, lb := range g.batches {
+		if lb.batch != nil {
+			lb.batch.Draw(target)
+			continue
+		}
+
+		for _, tileIndex := range lb.tiles {
+			t := l.DecodedTiles[tileIndex]
+			ts := t.Tileset
+			t.draw(tileIndex, ts.Columns, ts.numRows(), ts, target, l.TotalOffset(), mask)
+		}
+	}
+}
+
+func (l *TileLayer) findAnimatedTiles() {
+	l.animatedTiles = nil
+	for i, t := range l.DecodedTiles {
+		if !t.IsNil() && t.IsAnimated() {
+			l.animatedTiles = append(l.animatedTiles, i)
+		}
+	}
+}
+
+// updateAnimations will mark the chunks of the layer dirty which hold animated tiles that have changed frame since they
+// were drawn.
+func (l *TileLayer) updateAnimations() {
+	for _, tileIndex := range l.animatedTiles {
+		if t := l.DecodedTiles[tileIndex]; t.sprite != nil && t.spriteID != t.CurrentID() {
+			l.chunkAt(tileIndex).isDirty = true
+		}
+	}
+}
+
 func (l *TileLayer) decode(width, height int) ([]GID, error) {
 	log.WithField("Encoding", l.Data.Encoding).Debug("TileLayer.decode: determining encoding")
 
@@ -125,36 +611,65 @@ func (l *TileLayer) decode(width, height int) ([]GID, error) {
 		l.Tileset.setSprite()
 	}
 
-	switch l.Data.Encoding {
+	return l.decodeData(&l.Data, width, height)
+}
+
+// decodeChunks will decode the GIDs of each of the layers' chunks.  This is used instead of `TileLayer.decode` for
+// infinite maps.
+func (l *TileLayer) decodeChunks() error {
+	log.WithFields(log.Fields{"Encoding": l.Data.Encoding, "Chunk count": len(l.Data.Chunks)}).Debug("TileLayer.decodeChunks: decoding chunks")
+
+	l.SetStatic(true)
+	l.SetDirty(true)
+
+	if l.Tileset != nil {
+		l.Tileset.setSprite()
+	}
+
+	for _, c := range l.Data.Chunks {
+		gids, err := l.decodeData(c.data(&l.Data), c.Width, c.Height)
+		if err != nil {
+			log.WithError(err).WithField("Chunk", c).Error("TileLayer.decodeChunks: could not decode chunk")
+			return err
+		}
+
+		c.gids = gids
+	}
+
+	return nil
+}
+
+func (l *TileLayer) decodeData(d *Data, width, height int) ([]GID, error) {
+	switch d.Encoding {
 	case "csv":
-		return l.decodeLayerCSV(width, height)
+		return l.decodeLayerCSV(d, width, height)
 	case "base64":
-		return l.decodeLayerBase64(width, height)
+		return l.decodeLayerBase64(d, width, height)
 	case "":
 		// XML "encoding"
-		return l.decodeLayerXML(width, height)
+		return l.decodeLayerXML(d, width, height)
 	}
 
-	log.WithError(ErrUnknownEncoding).Error("TileLayer.decode: unrecognised encoding")
+	log.WithError(ErrUnknownEncoding).Error("TileLayer.decodeData: unrecognised encoding")
 	return nil, ErrUnknownEncoding
 }
 
-func (l *TileLayer) decodeLayerXML(width, height int) ([]GID, error) {
-	if len(l.Data.DataTiles) != width*height {
-		log.WithError(ErrInvalidDecodedDataLen).WithFields(log.Fields{"Length datatiles": len(l.Data.DataTiles), "W*H": width * height}).Error("TileLayer.decodeLayerXML: data length mismatch")
+func (l *TileLayer) decodeLayerXML(d *Data, width, height int) ([]GID, error) {
+	if len(d.DataTiles) != width*height {
+		log.WithError(ErrInvalidDecodedDataLen).WithFields(log.Fields{"Length datatiles": len(d.DataTiles), "W*H": width * height}).Error("TileLayer.decodeLayerXML: data length mismatch")
 		return nil, ErrInvalidDecodedDataLen
 	}
 
-	gids := make([]GID, len(l.Data.DataTiles))
+	gids := make([]GID, len(d.DataTiles))
 	for i := 0; i < len(gids); i++ {
-		gids[i] = l.Data.DataTiles[i].GID
+		gids[i] = d.DataTiles[i].GID
 	}
 
 	return gids, nil
 }
 
-func (l *TileLayer) decodeLayerCSV(width, height int) ([]GID, error) {
-	gids, err := l.Data.decodeCSV()
+func (l *TileLayer) decodeLayerCSV(d *Data, width, height int) ([]GID, error) {
+	gids, err := d.decodeCSV()
 	if err != nil {
 		log.WithError(err).Error("TileLayer.decodeLayerCSV: could not decode CSV")
 		return nil, err
@@ -168,8 +683,8 @@ func (l *TileLayer) decodeLayerCSV(width, height int) ([]GID, error) {
 	return gids, nil
 }
 
-func (l *TileLayer) decodeLayerBase64(width, height int) ([]GID, error) {
-	dataBytes, err := l.Data.decodeBase64()
+func (l *TileLayer) decodeLayerBase64(d *Data, width, height int) ([]GID, error) {
+	dataBytes, err := d.decodeBase64()
 	if err != nil {
 		log.WithError(err).Error("TileLayer.decodeLayerBase64: could not decode base64")
 		return nil, err
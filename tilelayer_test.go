@@ -1,7 +1,19 @@
 package tilepix
 
 import (
+	"fmt"
+	"image"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	// Required to decode the tileset PNG
+	_ "image/png"
+
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
 )
 
 func TestTileLayer_String(t *testing.T) {
@@ -30,3 +42,495 @@ func TestTileLayer_String(t *testing.T) {
 		})
 	}
 }
+
+func TestTileLayer_updateAnimations(t *testing.T) {
+	m, err := ReadFile("testdata/animation.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	animated := m.GetTileLayerByName("Animated")
+	static := m.GetTileLayerByName("Static")
+	if !animated.IsAnimated() || static.IsAnimated() {
+		t.Fatalf("Expected only the animated layer to be animated, got %t and %t", animated.IsAnimated(), static.IsAnimated())
+	}
+
+	// Batch both layers; static layers are not dirty once they have been drawn.
+	for _, l := range []*TileLayer{animated, static} {
+		if err := l.Draw(pixelgl.NewCanvas(m.Bounds())); err != nil {
+			t.Fatal(err)
+		}
+		if l.isDirty() {
+			t.Fatalf("Expected every chunk of layer %s to be batched", l.Name)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		dt            time.Duration
+		wantFrame     ID
+		wantAnimDirty bool
+	}{
+		{name: "same frame", dt: 50 * time.Millisecond, wantFrame: 0, wantAnimDirty: false},
+		{name: "next frame", dt: 60 * time.Millisecond, wantFrame: 1, wantAnimDirty: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.Update(tt.dt)
+
+			if got := animated.DecodedTiles[0].CurrentID(); got != tt.wantFrame {
+				t.Errorf("CurrentID() = %d, want %d", got, tt.wantFrame)
+			}
+			if got := animated.chunkAt(0).isDirty; got != tt.wantAnimDirty {
+				t.Errorf("animated chunk dirty = %t, want %t", got, tt.wantAnimDirty)
+			}
+			if static.isDirty() {
+				t.Error("static layer should not be dirty")
+			}
+		})
+	}
+}
+
+func TestMap_drawOrder(t *testing.T) {
+	tests := []struct {
+		orientation string
+		renderOrder string
+		staggerAxis string
+		want        []int
+	}{
+		{orientation: "orthogonal", renderOrder: "", want: []int{0, 1, 2, 3, 4, 5}},
+		{orientation: "orthogonal", renderOrder: "right-down", want: []int{0, 1, 2, 3, 4, 5}},
+		{orientation: "orthogonal", renderOrder: "right-up", want: []int{3, 4, 5, 0, 1, 2}},
+		{orientation: "orthogonal", renderOrder: "left-down", want: []int{2, 1, 0, 5, 4, 3}},
+		{orientation: "orthogonal", renderOrder: "left-up", want: []int{5, 4, 3, 2, 1, 0}},
+		{orientation: "isometric", renderOrder: "left-up", want: []int{0, 1, 2, 3, 4, 5}},
+		{orientation: "staggered", renderOrder: "right-down", staggerAxis: "x", want: []int{0, 2, 1, 3, 5, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.orientation+"/"+tt.renderOrder, func(t *testing.T) {
+			m := &Map{Orientation: tt.orientation, RenderOrder: tt.renderOrder, StaggerAxis: tt.staggerAxis, Width: 3, Height: 2}
+
+			got := m.drawOrder()
+			if len(got) != len(tt.want) {
+				t.Fatalf("drawOrder() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("drawOrder() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTileLayer_Draw_multipleTilesets(t *testing.T) {
+	m, err := ReadFile("testdata/multiple_tilesets.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := m.GetTileLayerByName("Tile Layer 1")
+	if l.Tileset != nil {
+		t.Errorf("Expected no single tileset for the layer, got %v", l.Tileset)
+	}
+
+	wantTilesets := []string{"white", "tileset", "tileset", "collection"}
+	for i, dt := range l.DecodedTiles {
+		if dt.Tileset == nil || dt.Tileset.Name != wantTilesets[i] {
+			t.Errorf("Expected tile %d from tileset %s, got %v", i, wantTilesets[i], dt.Tileset)
+		}
+	}
+
+	if err := l.Draw(pixelgl.NewCanvas(m.Bounds())); err != nil {
+		t.Fatalf("Could not draw layer: %v", err)
+	}
+
+	// batches returns the tileset and tiles of each batch of the layer, in the order they are drawn.
+	batches := func() []string {
+		var got []string
+		for _, lb := range l.chunks[0].batches {
+			got = append(got, fmt.Sprintf("%s%v", lb.tileset.Name, lb.tiles))
+		}
+		return got
+	}
+
+	// Consecutive tiles from the same tileset share a batch, the collection is drawn in its' place.
+	if want := []string{"white[0]", "tileset[1 2]", "collection[3]"}; !reflect.DeepEqual(batches(), want) {
+		t.Errorf("Expected batches %v, got %v", want, batches())
+	}
+
+	// A tile drawn after a tile from another tileset must be drawn over it, so starts a new batch of its' tileset.
+	white := *l.DecodedTiles[0]
+	l.DecodedTiles[2] = &white
+	l.SetDirty(true)
+	if err := l.Draw(pixelgl.NewCanvas(m.Bounds())); err != nil {
+		t.Fatalf("Could not draw layer: %v", err)
+	}
+	if want := []string{"white[0]", "tileset[1]", "white[2]", "collection[3]"}; !reflect.DeepEqual(batches(), want) {
+		t.Errorf("Expected batches %v, got %v", want, batches())
+	}
+}
+
+func TestTileLayer_chunksWithin(t *testing.T) {
+	files := []string{
+		"testdata/csv.tmx",
+		"testdata/groups.tmx",
+		"testdata/hexagonal.tmx",
+		"testdata/infinite.tmx",
+		"testdata/isometric.tmx",
+		"testdata/multiple_tilesets.tmx",
+		"testdata/render.tmx",
+		"testdata/staggered.tmx",
+		"testdata/tileoffset.tmx",
+	}
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			m, err := ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b := m.Bounds()
+			size := b.Size()
+			rects := []pixel.Rect{
+				b,
+				b.Moved(size.Scaled(2)),
+				pixel.R(b.Min.X, b.Min.Y, b.Min.X+size.X/3, b.Min.Y+size.Y/3),
+				pixel.R(b.Center().X-size.X/5, b.Center().Y-size.Y/4, b.Center().X+size.X/6, b.Center().Y+size.Y/7),
+				pixel.R(b.Max.X-size.X/4, b.Max.Y-size.Y/2, b.Max.X+10, b.Max.Y+10),
+			}
+
+			for _, l := range m.allTileLayers() {
+				for _, rect := range rects {
+					within := make(map[*batchChunk]bool)
+					for _, c := range l.chunksWithin(&rect) {
+						within[c] = true
+					}
+
+					// Every tile which intersects the rectangle, found by checking each tile, must be in a chunk.
+					r := rect.Moved(l.TotalOffset().Scaled(-1))
+					for i, dt := range l.DecodedTiles {
+						if !dt.IsNil() && rectsOverlap(dt.bounds(i, dt.Tileset), r) && !within[l.chunkAt(i)] {
+							t.Errorf("Layer %s: expected the chunk of tile %d to be within %v", l.Name, i, rect)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// drawnOrder returns the indices of the tiles of the layer, in the order they were last drawn by `TileLayer.Draw`.
+func drawnOrder(l *TileLayer) []int {
+	var order []int
+	add := func(g *batchGroup) {
+		for _, lb := range g.batches {
+			order = append(order, lb.tiles...)
+		}
+	}
+
+	for _, c := range l.chunksWithin(nil) {
+		add(&c.batchGroup)
+	}
+	for _, g := range l.deferred {
+		add(g)
+	}
+	return order
+}
+
+func TestTileLayer_Draw_order(t *testing.T) {
+	// Every third tile is from a collection of 32x32 images, on 16x16 cells, so overlaps the cells above and to the
+	// right; including the cells of other chunks.
+	var data strings.Builder
+	for i := 0; i < 40*40; i++ {
+		if i > 0 {
+			data.WriteByte(',')
+		}
+		if i%3 == 0 {
+			data.WriteString("16")
+		} else {
+			data.WriteString(strconv.Itoa(i%15 + 1))
+		}
+	}
+
+	tests := []struct {
+		orientation string
+		renderOrder string
+	}{
+		{orientation: "orthogonal", renderOrder: "right-down"},
+		{orientation: "orthogonal", renderOrder: "left-up"},
+		{orientation: "isometric", renderOrder: "right-down"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.orientation+"/"+tt.renderOrder, func(t *testing.T) {
+			tmx := fmt.Sprintf(`<map version="1.2" orientation="%s" renderorder="%s" width="40" height="40" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" source="tileset.tsx"/>
+ <tileset firstgid="16" name="collection" tilewidth="32" tileheight="32" tilecount="1" columns="0">
+  <tile id="0"><image width="32" height="32" source="singleWhite.png"/></tile>
+ </tileset>
+ <layer name="Ground" width="40" height="40"><data encoding="csv">%s</data></layer>
+</map>`, tt.orientation, tt.renderOrder, data.String())
+
+			m, err := Read(strings.NewReader(tmx), "testdata", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := m.GetTileLayerByName("Ground")
+			if err := l.Draw(pixelgl.NewCanvas(m.Bounds())); err != nil {
+				t.Fatal(err)
+			}
+
+			position := make(map[int]int)
+			for i, tileIndex := range m.drawOrder() {
+				position[tileIndex] = i
+			}
+
+			got := drawnOrder(l)
+			if len(got) != len(l.DecodedTiles) {
+				t.Fatalf("Expected %d tiles to be drawn, got %d", len(l.DecodedTiles), len(got))
+			}
+
+			// Each tile must be drawn after every tile it overlaps which is before it in the render order.
+			for i, a := range got {
+				ab := l.DecodedTiles[a].bounds(a, l.DecodedTiles[a].Tileset)
+				for _, b := range got[i+1:] {
+					if position[b] < position[a] && rectsOverlap(ab, l.DecodedTiles[b].bounds(b, l.DecodedTiles[b].Tileset)) {
+						t.Fatalf("Tile %d is drawn before tile %d, which it overlaps and follows in the render order", a, b)
+					}
+				}
+			}
+		})
+	}
+
+	// Tiles within their cells do not overlap the tiles of other chunks, so are drawn with their chunk.
+	grid := gridMap(t, 80, 80)
+	l := grid.GetTileLayerByName("Ground")
+	if err := l.Draw(pixelgl.NewCanvas(grid.Bounds())); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.deferred) != 0 {
+		t.Errorf("Expected no tiles of a grid to be deferred, got %d groups", len(l.deferred))
+	}
+}
+
+// gridMap returns an orthogonal map of width by height tiles of 16x16 pixels, from testdata/tileset.tsx.
+func gridMap(t *testing.T, width, height int) *Map {
+	t.Helper()
+
+	var data strings.Builder
+	for i := 0; i < width*height; i++ {
+		if i > 0 {
+			data.WriteByte(',')
+		}
+		data.WriteString(strconv.Itoa(i%15 + 1))
+	}
+
+	tmx := fmt.Sprintf(`<map version="1.2" orientation="orthogonal" renderorder="right-down" width="%d" height="%d" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" source="tileset.tsx"/>
+ <layer name="Ground" width="%d" height="%d"><data encoding="csv">%s</data></layer>
+</map>`, width, height, width, height, data.String())
+
+	m, err := Read(strings.NewReader(tmx), "testdata", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// countingTarget is a target which counts the vertices of the triangles drawn to it.
+type countingTarget struct {
+	vertices int
+}
+
+func (ct *countingTarget) MakeTriangles(t pixel.Triangles) pixel.TargetTriangles {
+	return &countedTriangles{Triangles: t, target: ct}
+}
+
+func (ct *countingTarget) MakePicture(p pixel.Picture) pixel.TargetPicture {
+	return countedPicture{p}
+}
+
+// countedTriangles adds the vertices of the triangles to the target each time they are drawn.
+type countedTriangles struct {
+	pixel.Triangles
+	target *countingTarget
+}
+
+func (ct *countedTriangles) Draw() {
+	ct.target.vertices += ct.Len()
+}
+
+// countedPicture draws triangles with a picture, counting them as if they were drawn without it.
+type countedPicture struct {
+	pixel.Picture
+}
+
+func (cp countedPicture) Draw(t pixel.TargetTriangles) {
+	t.Draw()
+}
+
+func TestTileLayer_DrawRect(t *testing.T) {
+	// A layer of 3x3 chunks, with a tile in every cell.
+	m := gridMap(t, 80, 80)
+	l := m.GetTileLayerByName("Ground")
+	l.SetStatic(true)
+
+	tests := []struct {
+		name string
+		rect pixel.Rect
+		// wantTiles is the number of tiles drawn; each tile is drawn with two triangles.
+		wantTiles int
+	}{
+		{name: "no tiles", rect: pixel.R(1300, 0, 1400, 100), wantTiles: 0},
+		{name: "part of the layer", rect: pixel.R(-100, 1200, 100, 1400), wantTiles: 32 * 32},
+		{name: "across chunks", rect: pixel.R(500, 1200, 520, 1210), wantTiles: 2 * 32 * 32},
+		{name: "whole layer", rect: m.Bounds(), wantTiles: 80 * 80},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := &countingTarget{}
+			if err := l.DrawRect(target, tt.rect); err != nil {
+				t.Fatal(err)
+			}
+			if got := target.vertices / 6; got != tt.wantTiles {
+				t.Errorf("Expected %d tiles to be drawn, got %d", tt.wantTiles, got)
+			}
+		})
+	}
+
+	// The rectangle only selects which tiles are drawn, so a static layer is not re-batched when it changes.
+	removed := l.DecodedTiles[1]
+	l.DecodedTiles[1] = &DecodedTile{Nil: true}
+	if err := l.DrawRect(&countingTarget{}, pixel.R(2, 1234, 40, 1246)); err != nil {
+		t.Fatal(err)
+	}
+	if l.isDirty() || !l.arranged {
+		t.Error("Expected the static layer not to be dirtied by changing the rectangle")
+	}
+	if got := len(l.chunks[0].batches[0].tiles); got != 32*32 {
+		t.Errorf("Expected the static layer to keep its' batches, got %d tiles batched", got)
+	}
+	l.DecodedTiles[1] = removed
+}
+
+func TestTileLayer_DrawRect_chunks(t *testing.T) {
+	// A layer of 3x3 chunks, the chunks on the right and bottom are smaller.
+	m := gridMap(t, 80, 80)
+	l := m.GetTileLayerByName("Ground")
+	l.SetStatic(true)
+	target := pixelgl.NewCanvas(m.Bounds())
+
+	dirty := func() []bool {
+		var got []bool
+		for _, c := range l.chunks {
+			got = append(got, c.isDirty)
+		}
+		return got
+	}
+
+	// Only the chunk at the top-left of the layer is within the rectangle.
+	if err := l.DrawRect(target, pixel.R(10, 1200, 100, 1270)); err != nil {
+		t.Fatal(err)
+	}
+	if want := []bool{false, true, true, true, true, true, true, true, true}; !reflect.DeepEqual(dirty(), want) {
+		t.Errorf("Expected only the top-left chunk to be batched, got dirty chunks %v", dirty())
+	}
+	if want := pixel.R(0, 768, 512, 1280); l.chunks[0].bounds != want {
+		t.Errorf("Expected chunk bounds %v, got %v", want, l.chunks[0].bounds)
+	}
+	if want := image.Rect(64, 64, 80, 80); l.chunks[8].cells != want {
+		t.Errorf("Expected chunk cells %v, got %v", want, l.chunks[8].cells)
+	}
+
+	// An animation changing frame only dirties its' chunk.
+	l.DecodedTiles[79*80+79].tile = &Tile{Animation: []*Frame{{TileID: 0, Duration: 10}, {TileID: 1, Duration: 10}}}
+	l.findAnimatedTiles()
+	if err := l.Draw(target); err != nil {
+		t.Fatal(err)
+	}
+	m.Update(15 * time.Millisecond)
+	if want := []bool{false, false, false, false, false, false, false, false, true}; !reflect.DeepEqual(dirty(), want) {
+		t.Errorf("Expected only the bottom-right chunk to be dirty, got dirty chunks %v", dirty())
+	}
+}
+
+func TestTileLayer_SetTileDirty(t *testing.T) {
+	// A layer of 3x3 chunks.
+	m := gridMap(t, 80, 80)
+	l := m.GetTileLayerByName("Ground")
+	l.SetStatic(true)
+	if err := l.Draw(&countingTarget{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dirty := func() []bool {
+		var got []bool
+		for _, c := range l.chunks {
+			got = append(got, c.isDirty)
+		}
+		return got
+	}
+
+	// Only the chunk holding a changed tile is re-batched.
+	changed := *l.DecodedTiles[79*80+79]
+	changed.ID = 4
+	l.DecodedTiles[79*80+79] = &changed
+	l.SetTileDirty(79*80 + 79)
+	if want := []bool{false, false, false, false, false, false, false, false, true}; !reflect.DeepEqual(dirty(), want) {
+		t.Errorf("Expected only the bottom-right chunk to be dirty, got dirty chunks %v", dirty())
+	}
+	if !l.arranged {
+		t.Error("Expected a tile which fits its' cell not to arrange the layer again")
+	}
+	if err := l.Draw(&countingTarget{}); err != nil {
+		t.Fatal(err)
+	}
+	if l.isDirty() {
+		t.Error("Expected the dirty chunk to be re-batched")
+	}
+
+	// A tile larger than its' cell may overlap tiles in other chunks, so the layer is arranged again.
+	large := *l.DecodedTiles[0]
+	ts := *large.Tileset
+	ts.TileWidth, ts.TileHeight = 32, 32
+	large.Tileset = &ts
+	l.DecodedTiles[0] = &large
+	l.SetTileDirty(0)
+	if l.arranged || !reflect.DeepEqual(dirty(), []bool{true, true, true, true, true, true, true, true, true}) {
+		t.Errorf("Expected a tile larger than its' cell to arrange the layer again, got dirty chunks %v", dirty())
+	}
+}
+
+func TestTileLayer_DrawRect_notStatic(t *testing.T) {
+	m := gridMap(t, 80, 80)
+	l := m.GetTileLayerByName("Ground")
+	l.SetStatic(false)
+
+	removed := l.DecodedTiles[0]
+	l.DecodedTiles[0] = &DecodedTile{Nil: true}
+	l.SetDirty(true)
+	if err := l.Draw(&countingTarget{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tiles stay arranged, so only the chunks within the rectangle are re-batched.
+	if err := l.DrawRect(&countingTarget{}, pixel.R(10, 1200, 100, 1270)); err != nil {
+		t.Fatal(err)
+	}
+	if !l.arranged {
+		t.Error("Expected a layer which is not static to stay arranged after it is drawn")
+	}
+	if !l.chunks[1].isDirty {
+		t.Error("Expected the chunks outside of the rectangle not to be re-batched")
+	}
+
+	// A tile set in the place of a nil tile is drawn without arranging the layer again.
+	l.DecodedTiles[0] = removed
+	target := &countingTarget{}
+	if err := l.Draw(target); err != nil {
+		t.Fatal(err)
+	}
+	if got := target.vertices / 6; got != 80*80 {
+		t.Errorf("Expected %d tiles to be drawn, got %d", 80*80, got)
+	}
+}
@@ -10,14 +10,13 @@ package tilepix
 */
 
 import (
-	"encoding/xml"
 	"errors"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
-
-	log "github.com/sirupsen/logrus"
+	"strings"
 )
 
 const (
@@ -44,6 +43,8 @@ func (o ObjectType) String() string {
 		return "Point"
 	case TileObj:
 		return "Tile"
+	case TextObj:
+		return "Text"
 	}
 
 	return "Unknown"
@@ -57,6 +58,7 @@ const (
 	RectangleObj
 	PointObj
 	TileObj
+	TextObj
 )
 
 // Errors which are returned from various places in the package.
@@ -67,7 +69,14 @@ var (
 	ErrInvalidGID            = errors.New("tmx: invalid GID")
 	ErrInvalidObjectType     = errors.New("tmx: the object type requested does not match this object")
 	ErrInvalidPointsField    = errors.New("tmx: invalid points string")
-	ErrInfiniteMap           = errors.New("tmx: infinite maps are not currently supported")
+	ErrInvalidColor          = errors.New("tmx: invalid color string")
+	ErrPropertyNotFound      = errors.New("tmx: property not found")
+	ErrObjectNotFound        = errors.New("tmx: object not found")
+	ErrMissingTileImage      = errors.New("tmx: tile in image collection has no image")
+	ErrInvalidTemplate       = errors.New("tmx: template has no object")
+	ErrNoRenderer            = errors.New("tmx: no renderer has been registered to draw the map")
+	// Deprecated: infinite maps are now supported, so ErrInfiniteMap is no longer returned.
+	ErrInfiniteMap = errors.New("tmx: infinite maps are not currently supported")
 )
 
 var (
@@ -91,89 +100,48 @@ func osOpen(name string) (http.File, error) {
 	return f, err
 }
 
-// Read will read, decode and initialise a Tiled Map from a data reader.
-// openFileFunc is used to retrieve tilesets and can be nil, in which case os.Open is used.
-func Read(r io.Reader, dir string, openFileFunc func(name string) (http.File, error)) (*Map, error) {
-	log.Debug("Read: reading from io.Reader")
-	if openFileFunc == nil {
-		openFileFunc = osOpen
-	}
-	var m Map
-	if err := xml.NewDecoder(r).Decode(&m); err != nil {
-		log.WithError(err).Error("Read: could not decode to Map")
-		return nil, err
-	}
-
-	m.dir = dir
-
-	if m.Infinite {
-		log.WithError(ErrInfiniteMap).Error("Read: map has attribute 'infinite=true', not supported")
-		return nil, ErrInfiniteMap
-	}
-
-	log.WithField("Tileset count", len(m.Tilesets)).Debug("Read: checking for tileset sources")
-	for i, ts := range m.Tilesets {
-		if ts.Source != "" {
-			f, err := openFileFunc(filepath.Join(dir, ts.Source))
-			if err != nil {
-				log.WithError(err).Error("Read: could not read tileset source")
-				return nil, err
-			}
-			sourceTs, err := readTileset(f, dir)
-			_ = f.Close()
-			if err != nil {
-				log.WithError(err).Error("Read: could not read tileset source")
-				return nil, err
-			}
-			sourceTs.FirstGID = ts.FirstGID
-			m.Tilesets[i] = sourceTs
-		}
-	}
-
-	if err := m.decodeLayers(); err != nil {
-		log.WithError(err).Error("Read: could not decode layers")
-		return nil, err
-	}
-
-	m.setParents()
-
-	log.WithField("TileLayer count", len(m.TileLayers)).Debug("Read: processing layer tilesets")
-	for _, l := range m.TileLayers {
-		tileset, isEmpty, usesMultipleTilesets := getTileset(l)
-		if usesMultipleTilesets {
-			log.Debug("Read: multiple tilesets in use")
-			continue
-		}
-		l.Empty, l.Tileset = isEmpty, tileset
-	}
-
-	// Tiled calculates co-ordinates from the top-left, flipping the y co-ordinate means we match the standard
-	// bottom-left calculation.
-	log.WithField("Object layer count", len(m.ObjectGroups)).Debug("Read: processing object layers")
-	for _, og := range m.ObjectGroups {
-		og.flipY()
+// fsOpen returns a function which opens files from the filesystem, for use as an openFileFunc.  Paths are converted to
+// the slash separated paths used by fs.FS.
+func fsOpen(fsys fs.FS) func(name string) (http.File, error) {
+	hfs := http.FS(fsys)
+	return func(name string) (http.File, error) {
+		return hfs.Open(filepath.ToSlash(filepath.Clean(name)))
 	}
+}
 
-	log.WithField("Tileset count", len(m.Tilesets)).Debug("Read: processing tilesets")
-	for _, ts := range m.Tilesets {
-		ts.setSprite()
-	}
+// Read will read, decode and initialise a Tiled Map from a data reader.
+// openFileFunc is used to retrieve tilesets, templates and images, and can be nil, in which case os.Open is used.
+func Read(r io.Reader, dir string, openFileFunc func(name string) (http.File, error)) (*Map, error) {
+	return NewLoader(openFileFunc).Read(r, dir)
+}
 
-	return &m, nil
+// ReadJSON will read, decode and initialise a Tiled Map from a data reader containing a map in the Tiled JSON format.
+// openFileFunc is used to retrieve tilesets, templates and images, and can be nil, in which case os.Open is used.
+func ReadJSON(r io.Reader, dir string, openFileFunc func(name string) (http.File, error)) (*Map, error) {
+	return NewLoader(openFileFunc).ReadJSON(r, dir)
 }
 
-// ReadFile will read, decode and initialise a Tiled Map from a file path.
+// ReadFile will read, decode and initialise a Tiled Map from a file path.  Files with a `.tmj` or `.json` extension are
+// read as Tiled JSON maps, all others are read as TMX.
+//
+// To share the tilesets and images used by several maps, read them with a `Loader` instead.
 func ReadFile(filePath string) (*Map, error) {
-	log.WithField("Filepath", filePath).Debug("ReadFile: reading file")
-
-	f, err := os.Open(filePath)
-	if err != nil {
-		log.WithError(err).Error("ReadFile: could not open file")
-		return nil, err
-	}
-	defer f.Close()
+	return NewLoader(nil).ReadFile(filePath)
+}
 
-	dir := filepath.Dir(filePath)
+// ReadFS will read, decode and initialise a Tiled Map from a file in the filesystem, such as an `embed.FS`.  All of the
+// files the map references, such as tilesets, templates and images, are also read from the filesystem, relative to the
+// file which references them.  Files with a `.tmj` or `.json` extension are read as Tiled JSON maps, all others are
+// read as TMX.
+func ReadFS(fsys fs.FS, filePath string) (*Map, error) {
+	return NewFSLoader(fsys).ReadFile(filePath)
+}
 
-	return Read(f, dir, nil)
+// isJSONPath returns whether the file path has an extension used by Tiled for JSON maps, tilesets and templates.
+func isJSONPath(filePath string) bool {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json", ".tmj", ".tsj", ".tj":
+		return true
+	}
+	return false
 }
@@ -5,9 +5,13 @@ import (
 	"encoding/xml"
 	"io"
 	"io/ioutil"
+	"os"
 	"testing"
+	"testing/fstest"
 
 	"github.com/bcvery1/tilepix"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -41,6 +45,12 @@ func TestReadFile(t *testing.T) {
 			want:     nil,
 			wantErr:  false,
 		},
+		{
+			name:     "base64-zstd",
+			filepath: "testdata/base64-zstd.tmx",
+			want:     nil,
+			wantErr:  false,
+		},
 		{
 			name:     "csv",
 			filepath: "testdata/csv.tmx",
@@ -63,7 +73,13 @@ func TestReadFile(t *testing.T) {
 			name:     "map is infinite",
 			filepath: "testdata/infinite.tmx",
 			want:     nil,
-			wantErr:  true,
+			wantErr:  false,
+		},
+		{
+			name:     "infinite map with chunks",
+			filepath: "testdata/infinite-chunks.tmx",
+			want:     nil,
+			wantErr:  false,
 		},
 		{
 			name:     "external tileset",
@@ -71,6 +87,24 @@ func TestReadFile(t *testing.T) {
 			want:     nil,
 			wantErr:  false,
 		},
+		{
+			name:     "json",
+			filepath: "testdata/poly.tmj",
+			want:     nil,
+			wantErr:  false,
+		},
+		{
+			name:     "json infinite map with chunks",
+			filepath: "testdata/infinite-chunks.tmj",
+			want:     nil,
+			wantErr:  false,
+		},
+		{
+			name:     "json external tileset",
+			filepath: "testdata/external_tileset.tmj",
+			want:     nil,
+			wantErr:  false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -119,6 +153,219 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestReadJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		tmxPath  string
+		jsonPath string
+	}{
+		{
+			name:     "base64-zlib with objects",
+			tmxPath:  "testdata/poly.tmx",
+			jsonPath: "testdata/poly.tmj",
+		},
+		{
+			name:     "external tileset",
+			tmxPath:  "testdata/external_tileset.tmx",
+			jsonPath: "testdata/external_tileset.tmj",
+		},
+		{
+			name:     "tile object groups",
+			tmxPath:  "testdata/tileobjectgroups.tmx",
+			jsonPath: "testdata/tileobjectgroups.tmj",
+		},
+		{
+			name:     "infinite map with chunks",
+			tmxPath:  "testdata/infinite-chunks.tmx",
+			jsonPath: "testdata/infinite-chunks.tmj",
+		},
+		{
+			name:     "group layers",
+			tmxPath:  "testdata/groups.tmx",
+			jsonPath: "testdata/groups.tmj",
+		},
+		{
+			name:     "text objects",
+			tmxPath:  "testdata/text.tmx",
+			jsonPath: "testdata/text.tmj",
+		},
+		{
+			name:     "tint and parallax",
+			tmxPath:  "testdata/parallax.tmx",
+			jsonPath: "testdata/parallax.tmj",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := tilepix.ReadFile(tt.tmxPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tilepix.ReadFile(tt.jsonPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			compareMaps(t, want, got)
+		})
+	}
+}
+
+func TestReadFile_compression(t *testing.T) {
+	want, err := tilepix.ReadFile("testdata/base64-gzip.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each file holds the same tile layer, using a different compression.
+	for _, name := range []string{"base64", "base64-zlib", "base64-zstd"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := tilepix.ReadFile("testdata/" + name + ".tmx")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			compareMaps(t, want, got)
+		})
+	}
+}
+
+func TestReadFS(t *testing.T) {
+	// The files are placed in a directory which does not exist on disk, so they can only be read from the filesystem.
+	fsys := fstest.MapFS{}
+	for _, name := range []string{"templates.tmx", "chest.tx", "area.tj", "tileset.tsx", "tileset.png", "singleWhite.png", "parallax.tmx", "parallax.tmj"} {
+		b, err := os.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys["levels/"+name] = &fstest.MapFile{Data: b}
+	}
+
+	target, err := pixelgl.NewWindow(pixelgl.WindowConfig{Bounds: pixel.R(0, 0, 100, 100)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "templates and tilesets", path: "levels/templates.tmx"},
+		{name: "images", path: "levels/parallax.tmx"},
+		{name: "json", path: "levels/parallax.tmj"},
+		{name: "missing", path: "levels/missing.tmx", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tilepix.ReadFS(fsys, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadFS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			want, err := tilepix.ReadFile("testdata/" + tt.path[len("levels/"):])
+			if err != nil {
+				t.Fatal(err)
+			}
+			compareMaps(t, want, m)
+
+			// Drawing loads the images of the tilesets and image layers.
+			if err := m.DrawAll(target, nil, pixel.IM); err != nil {
+				t.Fatalf("Could not draw map: %v", err)
+			}
+		})
+	}
+}
+
+// compareMaps will check that the map got is equivalent to the map want.  Tile and object layers in got are matched to
+// those in want by name, as want may contain layers which are not in got.
+func compareMaps(t *testing.T, want, got *tilepix.Map) {
+	t.Helper()
+
+	if got.Width != want.Width || got.Height != want.Height || got.TileWidth != want.TileWidth || got.TileHeight != want.TileHeight {
+		t.Errorf("Map dimensions mismatch, expected %v, got %v", want, got)
+	}
+	if got.BackgroundColor != want.BackgroundColor || got.ParallaxOriginX != want.ParallaxOriginX || got.ParallaxOriginY != want.ParallaxOriginY {
+		t.Errorf("Map background or parallax origin mismatch, expected %v, got %v", want, got)
+	}
+
+	if len(got.Properties) != len(want.Properties) {
+		t.Fatalf("Expected %d properties, got %d", len(want.Properties), len(got.Properties))
+	}
+	for i, p := range got.Properties {
+		wp := want.Properties[i]
+		if p.Name != wp.Name || p.Type != wp.Type || p.Value != wp.Value || len(p.Properties) != len(wp.Properties) {
+			t.Errorf("Property mismatch, expected %v (%s), got %v (%s)", wp, wp.Type, p, p.Type)
+		}
+	}
+
+	if len(got.Tilesets) != len(want.Tilesets) {
+		t.Fatalf("Expected %d tilesets, got %d", len(want.Tilesets), len(got.Tilesets))
+	}
+	for i, ts := range got.Tilesets {
+		if ts.String() != want.Tilesets[i].String() || ts.FirstGID != want.Tilesets[i].FirstGID || ts.Columns != want.Tilesets[i].Columns {
+			t.Errorf("Tileset mismatch, expected %v, got %v", want.Tilesets[i], ts)
+		}
+		if len(ts.TileObjects()) != len(want.Tilesets[i].TileObjects()) {
+			t.Errorf("Expected %d tile object groups, got %d", len(want.Tilesets[i].TileObjects()), len(ts.TileObjects()))
+		}
+	}
+
+	for _, l := range got.TileLayers {
+		wl := want.GetTileLayerByName(l.Name)
+		if wl == nil {
+			t.Fatalf("Unexpected tile layer '%s'", l.Name)
+		}
+		if l.TotalTint() != wl.TotalTint() || !l.TotalParallax().Eq(wl.TotalParallax()) {
+			t.Errorf("Tile layer '%s' tint or parallax mismatch", l.Name)
+		}
+		if len(l.DecodedTiles) != len(wl.DecodedTiles) {
+			t.Fatalf("Expected %d decoded tiles, got %d", len(wl.DecodedTiles), len(l.DecodedTiles))
+		}
+		for i, dt := range l.DecodedTiles {
+			wdt := wl.DecodedTiles[i]
+			if dt.String() != wdt.String() || dt.HorizontalFlip != wdt.HorizontalFlip || dt.VerticalFlip != wdt.VerticalFlip || dt.DiagonalFlip != wdt.DiagonalFlip {
+				t.Fatalf("Tile %d mismatch, expected %v, got %v", i, wdt, dt)
+			}
+		}
+	}
+
+	for _, og := range got.ObjectGroups {
+		wog := want.GetObjectLayerByName(og.Name)
+		if wog == nil {
+			t.Fatalf("Unexpected object layer '%s'", og.Name)
+		}
+		if og.String() != wog.String() {
+			t.Errorf("Object group mismatch, expected %v, got %v", wog, og)
+		}
+		for i, o := range og.Objects {
+			wo := wog.Objects[i]
+			if o.X != wo.X || o.Y != wo.Y || o.Width != wo.Width || o.Height != wo.Height {
+				t.Errorf("Object position mismatch, expected %v, got %v", wo, o)
+			}
+			if (o.Text == nil) != (wo.Text == nil) || o.Text != nil && *o.Text != *wo.Text {
+				t.Errorf("Object text mismatch, expected %v, got %v", wo.Text, o.Text)
+			}
+		}
+	}
+
+	if len(got.Groups) != len(want.Groups) {
+		t.Fatalf("Expected %d groups, got %d", len(want.Groups), len(got.Groups))
+	}
+	for i, g := range got.Groups {
+		wg := want.Groups[i]
+		if g.String() != wg.String() || g.IsVisible() != wg.IsVisible() || g.TotalOpacity() != wg.TotalOpacity() || !g.TotalOffset().Eq(wg.TotalOffset()) {
+			t.Errorf("Group mismatch, expected %v, got %v", wg, g)
+		}
+		if g.TotalTint() != wg.TotalTint() || !g.TotalParallax().Eq(wg.TotalParallax()) {
+			t.Errorf("Group '%s' tint or parallax mismatch", g.Name)
+		}
+	}
+}
+
 func getInput() io.Reader {
 	m := tilepix.Map{
 		Version:     "1.2",
@@ -4,6 +4,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 
@@ -33,9 +34,20 @@ type Tileset struct {
 	Tiles      []*Tile     `xml:"tile"`
 	Tilecount  int         `xml:"tilecount,attr"`
 	Columns    int         `xml:"columns,attr"`
+	// TileOffset is the offset, in pixels, applied when drawing tiles from the tileset.  This is nil if there is no
+	// offset.
+	TileOffset *TileOffset `xml:"tileoffset"`
+	// Grid is the grid used for the tiles in Tiled, nil if it is orthogonal and the size of the tiles.
+	Grid *Grid `xml:"grid"`
+	// ObjectAlignment is the point of tile objects which their position refers to; one of "topleft", "top",
+	// "topright", "left", "center", "right", "bottomleft", "bottom" or "bottomright".  When empty, this is "bottom" for
+	// isometric maps and "bottomleft" for all others.
+	ObjectAlignment string `xml:"objectalignment,attr"`
 
 	sprite  *pixel.Sprite
 	picture pixel.Picture
+	// tiles indexes Tiles by their ID.
+	tiles map[ID]*Tile
 
 	// parentMap is the map which contains this object
 	parentMap *Map
@@ -43,6 +55,21 @@ type Tileset struct {
 	dir string
 }
 
+// TileOffset is a TMX file structure holding the drawing offset of the tiles in a tileset.  Positive Y moves the tiles
+// down, as in Tiled.
+type TileOffset struct {
+	X int `xml:"x,attr"`
+	Y int `xml:"y,attr"`
+}
+
+// Grid is a TMX file structure holding the grid of a tileset, which is used to position the objects on its' tiles.
+type Grid struct {
+	// Orientation is either "orthogonal" or "isometric".
+	Orientation string `xml:"orientation,attr"`
+	Width       int    `xml:"width,attr"`
+	Height      int    `xml:"height,attr"`
+}
+
 func readTileset(r io.Reader, dir string) (*Tileset, error) {
 	log.Debug("readTileset: reading from io.Reader")
 
@@ -59,6 +86,26 @@ func readTileset(r io.Reader, dir string) (*Tileset, error) {
 	return validate(t)
 }
 
+// readTilesetSource will open and read an external tileset, relative to dir, using openFileFunc.  Tilesets with a `.tsj`
+// or `.json` extension are read as Tiled JSON tilesets, all others are read as TSX.  The paths within the tileset, such
+// as its' images, are relative to the tileset file itself.
+func readTilesetSource(openFileFunc func(name string) (http.File, error), dir, source string) (*Tileset, error) {
+	log.WithField("Source", source).Debug("readTilesetSource: reading tileset source")
+
+	path := filepath.Join(dir, source)
+	f, err := openFileFunc(path)
+	if err != nil {
+		log.WithError(err).Error("readTilesetSource: could not open tileset source")
+		return nil, err
+	}
+	defer f.Close()
+
+	if isJSONPath(source) {
+		return readTilesetJSON(f, filepath.Dir(path))
+	}
+	return readTileset(f, filepath.Dir(path))
+}
+
 func readTilesetFile(filePath string) (*Tileset, error) {
 	log.WithField("Filepath", filePath).Debug("readTilesetFile: reading file")
 
@@ -76,7 +123,7 @@ func readTilesetFile(filePath string) (*Tileset, error) {
 
 // GenerateTileObjectLayer will create a new ObjectGroup for the mapping of Objects to individual tiles.
 func (ts Tileset) GenerateTileObjectLayer(tileLayers []*TileLayer) ObjectGroup {
-	group := ObjectGroup{Name: fmt.Sprintf("%s-objectgroup", ts.Name)}
+	group := ObjectGroup{Name: fmt.Sprintf("%s-objectgroup", ts.Name), ParallaxX: 1, ParallaxY: 1}
 	objs := ts.TileObjects()
 
 	// Loop all TileLayers in map.
@@ -99,6 +146,10 @@ func (ts Tileset) GenerateTileObjectLayer(tileLayers []*TileLayer) ObjectGroup {
 			for _, obs := range og.Objects {
 				// Create a new Object based on the relative position of the Object and the DecodedTile.
 				o := *obs
+				if ts.isIsometricGrid() {
+					pos := ts.gridToImage(pixel.V(o.X, o.Y), ts.tileSize(t.ID))
+					o.X, o.Y = pos.X, pos.Y
+				}
 				tilePos := t.Position(ind, &ts)
 				o.X += tilePos.X
 				o.Y += tilePos.Y
@@ -111,8 +162,110 @@ func (ts Tileset) GenerateTileObjectLayer(tileLayers []*TileLayer) ObjectGroup {
 	return group
 }
 
+// GetProperty returns the Tilesets' Property by its name, or nil if there is no such property.
+func (ts *Tileset) GetProperty(name string) *Property {
+	return getProperty(ts.Properties, name)
+}
+
+// GetTile returns the Tilesets' Tile with the given ID, or nil if the tile has no properties, image, objects or
+// animation set.  Tiles are indexed the first time this is called, so `Tileset.SetTiles` must be used to change them.
+func (ts *Tileset) GetTile(id ID) *Tile {
+	if ts.tiles == nil {
+		ts.indexTiles()
+	}
+	return ts.tiles[id]
+}
+
+// SetTiles will replace the Tilesets' Tiles, and index them for `Tileset.GetTile`.  This should also be used after
+// changing the tiles within Tiles.  The tiles of the map from this tileset are updated to use the new tiles, and the
+// tile layers are set dirty.
+func (ts *Tileset) SetTiles(tiles []*Tile) {
+	ts.Tiles = tiles
+	ts.indexTiles()
+
+	m := ts.parentMap
+	if m == nil {
+		return
+	}
+
+	for _, t := range tiles {
+		t.setParent(m)
+	}
+
+	// update will point the decoded tile at its' new tile, returning whether it is from this tileset.
+	update := func(dt *DecodedTile) bool {
+		if dt == nil || dt.IsNil() || dt.Tileset != ts {
+			return false
+		}
+		dt.tile = ts.GetTile(dt.ID)
+		// The image of the tile may have changed.
+		dt.sprite = nil
+		return true
+	}
+
+	for _, l := range m.allTileLayers() {
+		changed := false
+		for _, dt := range l.DecodedTiles {
+			if update(dt) {
+				changed = true
+			}
+		}
+		if changed {
+			l.findAnimatedTiles()
+			l.SetDirty(true)
+		}
+	}
+	for _, og := range m.allObjectGroups() {
+		for _, o := range og.Objects {
+			update(o.tile)
+		}
+	}
+}
+
+// indexTiles will index Tiles by their ID, for `Tileset.GetTile`.
+func (ts *Tileset) indexTiles() {
+	ts.tiles = make(map[ID]*Tile, len(ts.Tiles))
+	for _, t := range ts.Tiles {
+		ts.tiles[t.ID] = t
+	}
+}
+
+// IsCollection returns whether the tileset is a collection of images, where each tile has its' own image instead of
+// being cut from a single tileset image.
+func (ts *Tileset) IsCollection() bool {
+	return ts.Image == nil
+}
+
+// clone returns a copy of the tileset, which can be used by a map without changing the original.  The tiles, images,
+// properties, tile objects and animations are copied; the pictures of any images which have been loaded are shared.
+func (ts *Tileset) clone() *Tileset {
+	c := *ts
+	c.Properties = cloneProperties(ts.Properties)
+	c.Image = ts.Image.clone()
+	c.tiles = nil
+	c.parentMap = nil
+
+	c.Tiles = nil
+	for _, t := range ts.Tiles {
+		tc := *t
+		tc.Properties = cloneProperties(t.Properties)
+		tc.Image = t.Image.clone()
+		tc.ObjectGroup = t.ObjectGroup.clone()
+		tc.parentMap = nil
+
+		tc.Animation = nil
+		for _, f := range t.Animation {
+			fc := *f
+			tc.Animation = append(tc.Animation, &fc)
+		}
+		c.Tiles = append(c.Tiles, &tc)
+	}
+
+	return &c
+}
+
 func validate(t Tileset) (*Tileset, error) {
-	if t.Columns < 1 {
+	if t.Columns < 1 && !t.IsCollection() {
 		return nil, fmt.Errorf("Tileset columns value not valid")
 	}
 	return &t, nil
@@ -151,22 +304,111 @@ func (ts *Tileset) setSprite() pixel.Picture {
 		return ts.picture
 	}
 
-	dir := ts.dir
-	if dir == "" {
-		dir = ts.parentMap.dir
+	if ts.IsCollection() {
+		// Each tile has its' own image, these are loaded instead of a single picture.
+		for _, t := range ts.Tiles {
+			if t.Image == nil {
+				continue
+			}
+			if err := t.Image.loadSprite(ts.imageDir()); err != nil {
+				log.WithError(err).WithField("Tile", t.ID).Error("Tileset.setSprite: could not load tile image")
+			}
+		}
+		return nil
 	}
 
-	sprite, pictureData, err := loadSpriteFromFile(filepath.Join(dir, ts.Image.Source))
-	if err != nil {
-		log.WithField("Filepath", filepath.Join(dir, ts.Image.Source)).WithError(err).Error("Tileset.setSprite: could not load sprite from file")
+	if err := ts.Image.loadSprite(ts.imageDir()); err != nil {
+		log.WithField("Image", ts.Image).WithError(err).Error("Tileset.setSprite: could not load sprite")
 		return nil
 	}
 
-	ts.sprite = sprite
-	ts.picture = pictureData
+	ts.sprite = ts.Image.sprite
+	ts.picture = ts.Image.picture
 	return ts.picture
 }
 
+// tilePicture returns the picture for the tile with the given ID, for tilesets which are a collection of images.
+func (ts *Tileset) tilePicture(id ID) (pixel.Picture, error) {
+	t := ts.GetTile(id)
+	if t == nil || t.Image == nil {
+		log.WithError(ErrMissingTileImage).WithField("ID", id).Error("Tileset.tilePicture: tile has no image")
+		return nil, ErrMissingTileImage
+	}
+
+	if err := t.Image.loadSprite(ts.imageDir()); err != nil {
+		log.WithError(err).WithField("ID", id).Error("Tileset.tilePicture: could not load tile image")
+		return nil, err
+	}
+
+	return t.Image.picture, nil
+}
+
+// tileSize returns the size of the tile with the given ID.  This is the tile size of the tileset, unless the tileset is
+// a collection of images, where each tile is the size of its' image.
+func (ts *Tileset) tileSize(id ID) pixel.Vec {
+	if !ts.IsCollection() {
+		return pixel.V(float64(ts.TileWidth), float64(ts.TileHeight))
+	}
+
+	t := ts.GetTile(id)
+	if t == nil || t.Image == nil {
+		return pixel.ZV
+	}
+	if t.Image.picture != nil {
+		return t.Image.picture.Bounds().Size()
+	}
+	return pixel.V(float64(t.Image.Width), float64(t.Image.Height))
+}
+
+// drawOffset returns the offset to draw the tilesets' tiles with, in game co-ordinates.
+func (ts *Tileset) drawOffset() pixel.Vec {
+	if ts.TileOffset == nil {
+		return pixel.ZV
+	}
+	return pixel.V(float64(ts.TileOffset.X), -float64(ts.TileOffset.Y))
+}
+
+// gridToImage converts a position on a tile, from an isometric tileset grid to the pixels of the tiles' image of the
+// given size.  Tiled positions the objects on tiles of isometric grids along the axes of the grid, with the image
+// centred on the bottom of a single grid cell.
+func (ts *Tileset) gridToImage(pos, size pixel.Vec) pixel.Vec {
+	gw, gh := float64(ts.Grid.Width), float64(ts.Grid.Height)
+	x, y := pos.X/gh, pos.Y/gh
+	return pixel.V((x-y)*gw/2+size.X/2, (x+y)*gh/2+size.Y-gh)
+}
+
+// isIsometricGrid returns whether the objects on the tilesets' tiles are positioned on an isometric grid.
+func (ts *Tileset) isIsometricGrid() bool {
+	return ts.Grid != nil && ts.Grid.Orientation == "isometric" && ts.Grid.Height > 0
+}
+
+// objectAlignment returns the point of the tilesets' tile objects which their position refers to.
+func (ts *Tileset) objectAlignment() string {
+	if ts.ObjectAlignment != "" && ts.ObjectAlignment != "unspecified" {
+		return ts.ObjectAlignment
+	}
+	if ts.parentMap != nil && ts.parentMap.isIsometric() {
+		return "bottom"
+	}
+	return "bottomleft"
+}
+
+// imageDir returns the directory which the tilesets' images are relative to.
+func (ts *Tileset) imageDir() string {
+	if ts.dir != "" {
+		return ts.dir
+	}
+	return ts.parentMap.dir
+}
+
+// numRows returns the number of rows of tiles in the tilesets' image.  This is zero for collections of images.
+func (ts *Tileset) numRows() int {
+	if ts.Columns < 1 {
+		return 0
+	}
+	return ts.Tilecount / ts.Columns
+}
+
 // TileObjects will return all ObjectGroups contained in Tiles.
 func (ts Tileset) TileObjects() map[ID]*ObjectGroup {
 	objs := make(map[ID]*ObjectGroup)
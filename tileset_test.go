@@ -44,3 +44,121 @@ func TestTileset_String(t *testing.T) {
 		})
 	}
 }
+
+func TestReadTilesetSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     string
+		source  string
+		wantDir string
+	}{
+		{
+			name:    "same directory",
+			dir:     "testdata",
+			source:  "tileset.tsx",
+			wantDir: "testdata",
+		},
+		{
+			name:    "sibling directory",
+			dir:     "testdata/levels",
+			source:  "../tilesets/terrain.tsx",
+			wantDir: "testdata/tilesets",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := readTilesetSource(osOpen, tt.dir, tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ts.dir != tt.wantDir {
+				t.Errorf("Expected tileset directory %s, got %s", tt.wantDir, ts.dir)
+			}
+
+			// The tilesets' image is relative to the tileset file.
+			if pic := ts.setSprite(); pic == nil {
+				t.Error("Could not load tileset image")
+			}
+		})
+	}
+}
+
+func TestTileset_GetTile(t *testing.T) {
+	tests := []struct {
+		name string
+		// edit changes the tiles of the tileset, after it has been indexed by GetTile, and returns the tile expected
+		// for ID 1.
+		edit func(ts *Tileset) *Tile
+	}{
+		{name: "unchanged", edit: func(ts *Tileset) *Tile { return ts.Tiles[1] }},
+		{
+			name: "slice replaced",
+			edit: func(ts *Tileset) *Tile {
+				ts.SetTiles([]*Tile{{ID: 0}, {ID: 1}})
+				return ts.Tiles[1]
+			},
+		},
+		{
+			name: "tile replaced",
+			edit: func(ts *Tileset) *Tile {
+				ts.Tiles[1] = &Tile{ID: 1}
+				ts.SetTiles(ts.Tiles)
+				return ts.Tiles[1]
+			},
+		},
+		{
+			name: "same slice, different tiles",
+			edit: func(ts *Tileset) *Tile {
+				tiles := ts.Tiles[:0]
+				tiles = append(tiles, &Tile{ID: 1}, &Tile{ID: 0})
+				ts.SetTiles(tiles)
+				return tiles[0]
+			},
+		},
+		{
+			name: "tile removed",
+			edit: func(ts *Tileset) *Tile {
+				ts.SetTiles(ts.Tiles[:1])
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := &Tileset{Tiles: []*Tile{{ID: 0}, {ID: 1}}}
+			ts.GetTile(1)
+			want := tt.edit(ts)
+
+			if got := ts.GetTile(1); got != want {
+				t.Errorf("Expected tile %p, got %p", want, got)
+			}
+			if got := ts.GetTile(2); got != nil {
+				t.Errorf("Expected no tile with ID 2, got %v", got)
+			}
+		})
+	}
+}
+
+func TestTileset_SetTiles(t *testing.T) {
+	m, err := ReadFile("testdata/animation.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := m.GetTileLayerByName("Animated")
+	ts := m.Tilesets[0]
+	if err := l.Draw(&countingTarget{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tiles of the map use the new tiles, so the tile without an animation is no longer animated.
+	ts.SetTiles([]*Tile{{ID: 0, Properties: []*Property{{Name: "solid", Value: "true"}}}})
+	if dt := l.DecodedTiles[0]; dt.IsAnimated() || dt.tile != ts.Tiles[0] {
+		t.Errorf("Expected the decoded tile to use the new tile, got %v", dt.tile)
+	}
+	if l.IsAnimated() {
+		t.Error("Expected the layer to no longer be animated")
+	}
+	if !l.isDirty() {
+		t.Error("Expected the layer to be set dirty")
+	}
+}
@@ -1,9 +1,12 @@
 package tilepix
 
 import (
+	"encoding/hex"
 	"image"
+	"image/color"
 	"io"
-	"os"
+	"net/http"
+	"strings"
 
 	"github.com/faiface/pixel"
 
@@ -31,15 +34,31 @@ func loadSprite(img io.Reader) (*pixel.Sprite, pixel.Picture, error) {
 	return sprite, pic, nil
 }
 
-func loadSpriteFromFile(path string) (*pixel.Sprite, pixel.Picture, error) {
-	f, err := os.OpenFile(path, os.O_RDONLY, 0444)
+// loadPictureFromFile will load the image at path, opening it with openFileFunc.
+func loadPictureFromFile(openFileFunc func(name string) (http.File, error), path string) (pixel.Picture, error) {
+	f, err := openFileFunc(path)
 	if err != nil {
-		log.WithError(err).WithField("Filepath", path).Error("loadSpriteFromFile: could not open file")
-		return nil, nil, err
+		log.WithError(err).WithField("Filepath", path).Error("loadPictureFromFile: could not open file")
+		return nil, err
 	}
 	defer f.Close()
 
-	return loadSprite(f)
+	return loadPicture(f)
+}
+
+// tintColor returns the colour to multiply a layer by, for the Tiled tint colour string.  Layers with no tint, or an
+// invalid tint, are drawn unchanged.
+func tintColor(s string) pixel.RGBA {
+	if s == "" {
+		return pixel.Alpha(1)
+	}
+
+	c, err := parseColor(s)
+	if err != nil {
+		log.WithError(err).Error("tintColor: could not parse tint colour, drawing untinted")
+		return pixel.Alpha(1)
+	}
+	return pixel.ToRGBA(c)
 }
 
 func tileIDToCoord(tID ID, numColumns int, numRows int) (x int, y int) {
@@ -56,3 +75,41 @@ func indexToGamePos(idx int, width int, height int) pixel.Vec {
 	)
 	return gamePos
 }
+
+// rectsOverlap returns whether the rectangles share any area.
+func rectsOverlap(a, b pixel.Rect) bool {
+	return a.Min.X < b.Max.X && b.Min.X < a.Max.X && a.Min.Y < b.Max.Y && b.Min.Y < a.Max.Y
+}
+
+// overlapsAny returns whether the rectangle overlaps any of the rectangles.
+func overlapsAny(rects []pixel.Rect, r pixel.Rect) bool {
+	for _, o := range rects {
+		if rectsOverlap(o, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseColor parses a Tiled colour string, which is in the format `#AARRGGBB`, or `#RRGGBB` when fully opaque.  The
+// leading '#' is optional.  Tiled colours are not premultiplied, so they are converted to a premultiplied color.RGBA.
+func parseColor(s string) (color.RGBA, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil {
+		log.WithError(err).WithField("Color", s).Error("parseColor: could not decode hex string")
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	var c color.NRGBA
+	switch len(b) {
+	case 3:
+		c = color.NRGBA{R: b[0], G: b[1], B: b[2], A: 0xff}
+	case 4:
+		c = color.NRGBA{R: b[1], G: b[2], B: b[3], A: b[0]}
+	default:
+		log.WithError(ErrInvalidColor).WithField("Color", s).Error("parseColor: unexpected length")
+		return color.RGBA{}, ErrInvalidColor
+	}
+
+	return color.RGBAModel.Convert(c).(color.RGBA), nil
+}
@@ -0,0 +1,748 @@
+package tilepix
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/faiface/pixel"
+	"github.com/klauspost/compress/zstd"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// chunkSize is the width and height, in tiles, of the chunks written for infinite maps.  This matches the size used by
+// Tiled.
+const chunkSize = 16
+
+// WriteOptions is used to configure how a map is written with `Map.Write`.
+type WriteOptions struct {
+	// Encoding is the encoding used for tile layer data.  This can be "csv", "base64", or "xml".  Defaults to "csv".
+	Encoding string
+	// Compression is the compression used for base64 encoded tile layer data.  This can be "gzip", "zlib", "zstd" or
+	// empty for no compression.
+	Compression string
+}
+
+// The structures below mirror the TMX format for writing.  They are used instead of marshalling the Map directly, so
+// that the in-memory changes made when reading (such as flipping object co-ordinates) can be undone without modifying
+// the Map.
+
+type tmxMap struct {
+	XMLName         xml.Name       `xml:"map"`
+	Version         string         `xml:"version,attr,omitempty"`
+	Orientation     string         `xml:"orientation,attr,omitempty"`
+	Width           int            `xml:"width,attr"`
+	Height          int            `xml:"height,attr"`
+	TileWidth       int            `xml:"tilewidth,attr"`
+	TileHeight      int            `xml:"tileheight,attr"`
+	RenderOrder     string         `xml:"renderorder,attr,omitempty"`
+	StaggerAxis     string         `xml:"staggeraxis,attr,omitempty"`
+	StaggerIndex    string         `xml:"staggerindex,attr,omitempty"`
+	HexSideLength   int            `xml:"hexsidelength,attr,omitempty"`
+	BackgroundColor string         `xml:"backgroundcolor,attr,omitempty"`
+	ParallaxOriginX float64        `xml:"parallaxoriginx,attr,omitempty"`
+	ParallaxOriginY float64        `xml:"parallaxoriginy,attr,omitempty"`
+	Infinite        int            `xml:"infinite,attr"`
+	Properties      *tmxProperties `xml:"properties"`
+	Tilesets        []*tmxTileset  `xml:"tileset"`
+	// Layers holds the layers in order; each is one of the tmx layer types.
+	Layers []interface{}
+}
+
+type tmxTileset struct {
+	FirstGID        GID            `xml:"firstgid,attr"`
+	Source          string         `xml:"source,attr,omitempty"`
+	Name            string         `xml:"name,attr,omitempty"`
+	TileWidth       int            `xml:"tilewidth,attr,omitempty"`
+	TileHeight      int            `xml:"tileheight,attr,omitempty"`
+	Spacing         int            `xml:"spacing,attr,omitempty"`
+	Margin          int            `xml:"margin,attr,omitempty"`
+	Tilecount       int            `xml:"tilecount,attr,omitempty"`
+	Columns         int            `xml:"columns,attr,omitempty"`
+	ObjectAlignment string         `xml:"objectalignment,attr,omitempty"`
+	TileOffset      *TileOffset    `xml:"tileoffset"`
+	Grid            *Grid          `xml:"grid"`
+	Properties      *tmxProperties `xml:"properties"`
+	Image           *tmxImage      `xml:"image"`
+	Tiles           []*tmxTile     `xml:"tile"`
+}
+
+type tmxProperties struct {
+	Properties []*tmxProperty `xml:"property"`
+}
+
+type tmxProperty struct {
+	Name         string         `xml:"name,attr"`
+	Type         string         `xml:"type,attr,omitempty"`
+	PropertyType string         `xml:"propertytype,attr,omitempty"`
+	Value        string         `xml:"value,attr"`
+	Properties   *tmxProperties `xml:"properties"`
+}
+
+type tmxImage struct {
+	Format string   `xml:"format,attr,omitempty"`
+	Source string   `xml:"source,attr,omitempty"`
+	Trans  string   `xml:"trans,attr,omitempty"`
+	Width  int      `xml:"width,attr,omitempty"`
+	Height int      `xml:"height,attr,omitempty"`
+	Data   *tmxData `xml:"data"`
+}
+
+type tmxTile struct {
+	ID          ID              `xml:"id,attr"`
+	Properties  *tmxProperties  `xml:"properties"`
+	Image       *tmxImage       `xml:"image"`
+	ObjectGroup *tmxObjectGroup `xml:"objectgroup"`
+	Animation   *tmxAnimation   `xml:"animation"`
+}
+
+type tmxAnimation struct {
+	Frames []*Frame `xml:"frame"`
+}
+
+type tmxTileLayer struct {
+	XMLName    xml.Name       `xml:"layer"`
+	Name       string         `xml:"name,attr"`
+	Width      int            `xml:"width,attr"`
+	Height     int            `xml:"height,attr"`
+	Opacity    string         `xml:"opacity,attr,omitempty"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	OffSetX    float64        `xml:"offsetx,attr,omitempty"`
+	OffSetY    float64        `xml:"offsety,attr,omitempty"`
+	ParallaxX  string         `xml:"parallaxx,attr,omitempty"`
+	ParallaxY  string         `xml:"parallaxy,attr,omitempty"`
+	TintColor  string         `xml:"tintcolor,attr,omitempty"`
+	Properties *tmxProperties `xml:"properties"`
+	Data       *tmxData       `xml:"data"`
+}
+
+type tmxData struct {
+	Encoding    string         `xml:"encoding,attr,omitempty"`
+	Compression string         `xml:"compression,attr,omitempty"`
+	Data        string         `xml:",innerxml"`
+	DataTiles   []*tmxDataTile `xml:"tile"`
+	Chunks      []*tmxChunk    `xml:"chunk"`
+}
+
+type tmxChunk struct {
+	X         int            `xml:"x,attr"`
+	Y         int            `xml:"y,attr"`
+	Width     int            `xml:"width,attr"`
+	Height    int            `xml:"height,attr"`
+	Data      string         `xml:",innerxml"`
+	DataTiles []*tmxDataTile `xml:"tile"`
+}
+
+type tmxDataTile struct {
+	GID GID `xml:"gid,attr,omitempty"`
+}
+
+type tmxObjectGroup struct {
+	XMLName    xml.Name       `xml:"objectgroup"`
+	Name       string         `xml:"name,attr,omitempty"`
+	Color      string         `xml:"color,attr,omitempty"`
+	Opacity    string         `xml:"opacity,attr,omitempty"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	OffSetX    float64        `xml:"offsetx,attr,omitempty"`
+	OffSetY    float64        `xml:"offsety,attr,omitempty"`
+	ParallaxX  string         `xml:"parallaxx,attr,omitempty"`
+	ParallaxY  string         `xml:"parallaxy,attr,omitempty"`
+	TintColor  string         `xml:"tintcolor,attr,omitempty"`
+	Properties *tmxProperties `xml:"properties"`
+	Objects    []*tmxObject   `xml:"object"`
+}
+
+type tmxObject struct {
+	ID         ID             `xml:"id,attr"`
+	Name       string         `xml:"name,attr,omitempty"`
+	Type       string         `xml:"type,attr,omitempty"`
+	GID        ID             `xml:"gid,attr,omitempty"`
+	Template   string         `xml:"template,attr,omitempty"`
+	X          float64        `xml:"x,attr"`
+	Y          float64        `xml:"y,attr"`
+	Width      float64        `xml:"width,attr,omitempty"`
+	Height     float64        `xml:"height,attr,omitempty"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	Properties *tmxProperties `xml:"properties"`
+	Ellipse    *struct{}      `xml:"ellipse"`
+	Point      *struct{}      `xml:"point"`
+	Polygon    *Polygon       `xml:"polygon"`
+	PolyLine   *PolyLine      `xml:"polyline"`
+	Text       *tmxText       `xml:"text"`
+}
+
+type tmxText struct {
+	Text       string `xml:",chardata"`
+	FontFamily string `xml:"fontfamily,attr,omitempty"`
+	PixelSize  int    `xml:"pixelsize,attr,omitempty"`
+	Wrap       string `xml:"wrap,attr,omitempty"`
+	Color      string `xml:"color,attr,omitempty"`
+	Bold       string `xml:"bold,attr,omitempty"`
+	Italic     string `xml:"italic,attr,omitempty"`
+	Underline  string `xml:"underline,attr,omitempty"`
+	Strikeout  string `xml:"strikeout,attr,omitempty"`
+	Kerning    string `xml:"kerning,attr,omitempty"`
+	HAlign     string `xml:"halign,attr,omitempty"`
+	VAlign     string `xml:"valign,attr,omitempty"`
+}
+
+type tmxImageLayer struct {
+	XMLName    xml.Name       `xml:"imagelayer"`
+	Name       string         `xml:"name,attr"`
+	Locked     string         `xml:"locked,attr,omitempty"`
+	Opacity    string         `xml:"opacity,attr,omitempty"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	OffSetX    float64        `xml:"offsetx,attr,omitempty"`
+	OffSetY    float64        `xml:"offsety,attr,omitempty"`
+	ParallaxX  string         `xml:"parallaxx,attr,omitempty"`
+	ParallaxY  string         `xml:"parallaxy,attr,omitempty"`
+	TintColor  string         `xml:"tintcolor,attr,omitempty"`
+	Properties *tmxProperties `xml:"properties"`
+	Image      *tmxImage      `xml:"image"`
+}
+
+type tmxGroup struct {
+	XMLName    xml.Name       `xml:"group"`
+	Name       string         `xml:"name,attr"`
+	Opacity    string         `xml:"opacity,attr,omitempty"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	OffSetX    float64        `xml:"offsetx,attr,omitempty"`
+	OffSetY    float64        `xml:"offsety,attr,omitempty"`
+	ParallaxX  string         `xml:"parallaxx,attr,omitempty"`
+	ParallaxY  string         `xml:"parallaxy,attr,omitempty"`
+	TintColor  string         `xml:"tintcolor,attr,omitempty"`
+	Properties *tmxProperties `xml:"properties"`
+	// Layers holds the layers in order; each is one of the tmx layer types.
+	Layers []interface{}
+}
+
+// Write will encode the map as TMX to the writer.  The tile layers are encoded from their DecodedTiles, so any changes
+// made to the map after reading are included.  External tilesets are written as references to their source, the
+// tileset files themselves are not written.
+//
+// opts can be nil, in which case tile layer data is CSV encoded.
+func (m *Map) Write(w io.Writer, opts *WriteOptions) error {
+	if opts == nil {
+		opts = &WriteOptions{}
+	}
+
+	tm, err := m.toTMX(opts)
+	if err != nil {
+		log.WithError(err).Error("Map.Write: could not convert map")
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		log.WithError(err).Error("Map.Write: could not write XML header")
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(tm); err != nil {
+		log.WithError(err).Error("Map.Write: could not encode map")
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// WriteFile will encode the map as TMX to the file path, creating or truncating the file.
+func (m *Map) WriteFile(filePath string, opts *WriteOptions) error {
+	log.WithField("Filepath", filePath).Debug("Map.WriteFile: writing file")
+
+	f, err := os.Create(filePath)
+	if err != nil {
+		log.WithError(err).Error("Map.WriteFile: could not create file")
+		return err
+	}
+
+	if err := m.Write(f, opts); err != nil {
+		_ = f.Close()
+		log.WithError(err).Error("Map.WriteFile: could not write map")
+		return err
+	}
+
+	return f.Close()
+}
+
+func (m *Map) toTMX(opts *WriteOptions) (*tmxMap, error) {
+	tm := &tmxMap{
+		Version:         m.Version,
+		Orientation:     m.Orientation,
+		Width:           m.Width,
+		Height:          m.Height,
+		TileWidth:       m.TileWidth,
+		TileHeight:      m.TileHeight,
+		RenderOrder:     m.RenderOrder,
+		StaggerAxis:     m.StaggerAxis,
+		StaggerIndex:    m.StaggerIndex,
+		HexSideLength:   m.HexSideLength,
+		BackgroundColor: m.BackgroundColor,
+		ParallaxOriginX: m.ParallaxOriginX,
+		ParallaxOriginY: m.ParallaxOriginY,
+		Properties:      toTMXProperties(m.Properties),
+	}
+	if m.Infinite {
+		tm.Infinite = 1
+	}
+
+	for _, ts := range m.Tilesets {
+		tm.Tilesets = append(tm.Tilesets, ts.toTMX())
+	}
+
+	layers, err := layersToTMX(m.Layers(), m, opts)
+	if err != nil {
+		log.WithError(err).Error("Map.toTMX: could not convert layers")
+		return nil, err
+	}
+	tm.Layers = layers
+
+	return tm, nil
+}
+
+// layersToTMX will convert the layers for writing, keeping their order.
+func layersToTMX(ls []Layer, m *Map, opts *WriteOptions) ([]interface{}, error) {
+	var tls []interface{}
+	for _, l := range ls {
+		switch l := l.(type) {
+		case *TileLayer:
+			tl, err := l.toTMX(m, opts)
+			if err != nil {
+				log.WithError(err).WithField("Layer", l.Name).Error("layersToTMX: could not convert tile layer")
+				return nil, err
+			}
+			tls = append(tls, tl)
+		case *ObjectGroup:
+			tls = append(tls, l.toTMX(m))
+		case *ImageLayer:
+			tls = append(tls, l.toTMX())
+		case *Group:
+			tg, err := l.toTMX(m, opts)
+			if err != nil {
+				log.WithError(err).WithField("Group", l.Name).Error("layersToTMX: could not convert group")
+				return nil, err
+			}
+			tls = append(tls, tg)
+		}
+	}
+
+	return tls, nil
+}
+
+func (g *Group) toTMX(m *Map, opts *WriteOptions) (*tmxGroup, error) {
+	layers, err := layersToTMX(g.Layers(), m, opts)
+	if err != nil {
+		log.WithError(err).Error("Group.toTMX: could not convert layers")
+		return nil, err
+	}
+
+	return &tmxGroup{
+		Name:       g.Name,
+		Opacity:    opacityAttr(float64(g.Opacity)),
+		Visible:    visibleAttr(g.Visible),
+		OffSetX:    g.OffSetX,
+		OffSetY:    g.OffSetY,
+		ParallaxX:  parallaxAttr(g.ParallaxX),
+		ParallaxY:  parallaxAttr(g.ParallaxY),
+		TintColor:  g.TintColor,
+		Properties: toTMXProperties(g.Properties),
+		Layers:     layers,
+	}, nil
+}
+
+func (im *ImageLayer) toTMX() *tmxImageLayer {
+	return &tmxImageLayer{
+		Name:       im.Name,
+		Locked:     boolAttr(im.Locked),
+		Opacity:    opacityAttr(im.Opacity),
+		Visible:    visibleAttr(im.Visible),
+		OffSetX:    im.OffSetX,
+		OffSetY:    im.OffSetY,
+		ParallaxX:  parallaxAttr(im.ParallaxX),
+		ParallaxY:  parallaxAttr(im.ParallaxY),
+		TintColor:  im.TintColor,
+		Properties: toTMXProperties(im.Properties),
+		Image:      toTMXImage(im.Image),
+	}
+}
+
+func (ts *Tileset) toTMX() *tmxTileset {
+	if ts.Source != "" {
+		return &tmxTileset{FirstGID: ts.FirstGID, Source: ts.Source}
+	}
+
+	tt := &tmxTileset{
+		FirstGID:   ts.FirstGID,
+		Name:       ts.Name,
+		TileWidth:  ts.TileWidth,
+		TileHeight: ts.TileHeight,
+		Spacing:    ts.Spacing,
+		Margin:     ts.Margin,
+		Tilecount:  ts.Tilecount,
+		Columns:    ts.Columns,
+		Properties: toTMXProperties(ts.Properties),
+		Image:      toTMXImage(ts.Image),
+		TileOffset: ts.TileOffset,
+		Grid:       ts.Grid,
+	}
+	if ts.ObjectAlignment != "unspecified" {
+		// Unspecified is the default in Tiled, so is omitted.
+		tt.ObjectAlignment = ts.ObjectAlignment
+	}
+
+	for _, t := range ts.Tiles {
+		tile := &tmxTile{ID: t.ID, Properties: toTMXProperties(t.Properties), Image: toTMXImage(t.Image)}
+		if t.IsAnimated() {
+			tile.Animation = &tmxAnimation{Frames: t.Animation}
+		}
+		if t.ObjectGroup != nil {
+			// Objects on tiles are not flipped when read, they are relative to the tile.
+			tile.ObjectGroup = t.ObjectGroup.toTMX(nil)
+		}
+		tt.Tiles = append(tt.Tiles, tile)
+	}
+
+	return tt
+}
+
+func (l *TileLayer) toTMX(m *Map, opts *WriteOptions) (*tmxTileLayer, error) {
+	tl := &tmxTileLayer{
+		Name:       l.Name,
+		Width:      m.Width,
+		Height:     m.Height,
+		Opacity:    opacityAttr(float64(l.Opacity)),
+		Visible:    visibleAttr(l.Visible),
+		OffSetX:    l.OffSetX,
+		OffSetY:    l.OffSetY,
+		ParallaxX:  parallaxAttr(l.ParallaxX),
+		ParallaxY:  parallaxAttr(l.ParallaxY),
+		TintColor:  l.TintColor,
+		Properties: toTMXProperties(l.Properties),
+		Data:       &tmxData{},
+	}
+
+	gids := make([]GID, len(l.DecodedTiles))
+	for i, t := range l.DecodedTiles {
+		gids[i] = t.gid()
+	}
+
+	if !m.Infinite {
+		data, dataTiles, err := encodeGIDs(gids, m.Width, opts)
+		if err != nil {
+			log.WithError(err).Error("TileLayer.toTMX: could not encode layer data")
+			return nil, err
+		}
+
+		tl.Data.Data, tl.Data.DataTiles = data, dataTiles
+	} else {
+		chunks, err := encodeChunks(gids, m, opts)
+		if err != nil {
+			log.WithError(err).Error("TileLayer.toTMX: could not encode layer chunks")
+			return nil, err
+		}
+
+		tl.Data.Chunks = chunks
+	}
+
+	if opts.Encoding != "xml" {
+		tl.Data.Encoding = opts.Encoding
+		if tl.Data.Encoding == "" {
+			tl.Data.Encoding = "csv"
+		}
+	}
+	if tl.Data.Encoding == "base64" {
+		tl.Data.Compression = opts.Compression
+	}
+
+	return tl, nil
+}
+
+// toTMX will convert the ObjectGroup for writing.  If m is not nil, the changes made to object positions when the map
+// was read are undone.
+func (og *ObjectGroup) toTMX(m *Map) *tmxObjectGroup {
+	tog := &tmxObjectGroup{
+		Name:       og.Name,
+		Color:      og.Color,
+		Opacity:    opacityAttr(float64(og.Opacity)),
+		Visible:    visibleAttr(og.Visible),
+		OffSetX:    og.OffSetX,
+		OffSetY:    og.OffSetY,
+		ParallaxX:  parallaxAttr(og.ParallaxX),
+		ParallaxY:  parallaxAttr(og.ParallaxY),
+		TintColor:  og.TintColor,
+		Properties: toTMXProperties(og.Properties),
+	}
+
+	for _, o := range og.Objects {
+		to := &tmxObject{
+			ID:         o.ID,
+			Name:       o.Name,
+			Type:       o.Type,
+			GID:        o.GID,
+			Template:   o.Template,
+			X:          o.X,
+			Y:          o.Y,
+			Width:      o.Width,
+			Height:     o.Height,
+			Visible:    visibleAttr(o.Visible),
+			Properties: toTMXProperties(o.Properties),
+			Ellipse:    o.Ellipse,
+			Point:      o.Point,
+			Polygon:    o.Polygon,
+			PolyLine:   o.PolyLine,
+			Text:       toTMXText(o.Text),
+		}
+
+		if m != nil {
+			// Reverse the layer offset and `Object.flipY`, both applied in `ObjectGroup.flipY`.
+			pos := pixel.V(o.X, o.Y).Sub(og.TotalOffset()).Sub(o.anchor())
+			if m.isIsometric() {
+				tile := m.WorldToTile(pos).Scaled(float64(m.TileHeight))
+				to.X, to.Y = tile.X, tile.Y
+			} else {
+				to.X, to.Y = pos.X, m.originY()-pos.Y
+			}
+		}
+
+		tog.Objects = append(tog.Objects, to)
+	}
+
+	return tog
+}
+
+// encodeChunks will split the GIDs of an infinite maps' layer into chunks, aligned to a grid of `chunkSize`, which cover
+// the area of the map.
+func encodeChunks(gids []GID, m *Map, opts *WriteOptions) ([]*tmxChunk, error) {
+	var chunks []*tmxChunk
+
+	for cy := floorTo(m.StartY, chunkSize); cy < m.StartY+m.Height; cy += chunkSize {
+		for cx := floorTo(m.StartX, chunkSize); cx < m.StartX+m.Width; cx += chunkSize {
+			chunkGIDs := make([]GID, chunkSize*chunkSize)
+			for y := 0; y < chunkSize; y++ {
+				for x := 0; x < chunkSize; x++ {
+					mx, my := cx+x-m.StartX, cy+y-m.StartY
+					if mx < 0 || my < 0 || mx >= m.Width || my >= m.Height {
+						continue
+					}
+					chunkGIDs[y*chunkSize+x] = gids[my*m.Width+mx]
+				}
+			}
+
+			data, dataTiles, err := encodeGIDs(chunkGIDs, chunkSize, opts)
+			if err != nil {
+				log.WithError(err).Error("encodeChunks: could not encode chunk data")
+				return nil, err
+			}
+
+			chunks = append(chunks, &tmxChunk{
+				X:         cx,
+				Y:         cy,
+				Width:     chunkSize,
+				Height:    chunkSize,
+				Data:      data,
+				DataTiles: dataTiles,
+			})
+		}
+	}
+
+	return chunks, nil
+}
+
+// encodeGIDs will encode the GIDs of a layer or chunk as per the options.  For XML encoding the returned string is
+// empty, and the DataTiles are returned instead.
+func encodeGIDs(gids []GID, width int, opts *WriteOptions) (string, []*tmxDataTile, error) {
+	switch opts.Encoding {
+	case "", "csv":
+		return encodeCSV(gids, width), nil, nil
+	case "base64":
+		data, err := encodeBase64(gids, opts.Compression)
+		return data, nil, err
+	case "xml":
+		dataTiles := make([]*tmxDataTile, len(gids))
+		for i, gid := range gids {
+			dataTiles[i] = &tmxDataTile{GID: gid}
+		}
+		return "", dataTiles, nil
+	}
+
+	log.WithError(ErrUnknownEncoding).WithField("Encoding", opts.Encoding).Error("encodeGIDs: unrecognised encoding")
+	return "", nil, ErrUnknownEncoding
+}
+
+func encodeCSV(gids []GID, width int) string {
+	var sb strings.Builder
+	sb.WriteString("\n")
+
+	for i, gid := range gids {
+		sb.WriteString(strconv.FormatUint(uint64(gid), 10))
+		if i < len(gids)-1 {
+			sb.WriteString(",")
+		}
+		if (i+1)%width == 0 {
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+func encodeBase64(gids []GID, compression string) (string, error) {
+	var buf bytes.Buffer
+
+	var comw io.WriteCloser
+	switch compression {
+	case "gzip":
+		comw = gzip.NewWriter(&buf)
+	case "zlib":
+		comw = zlib.NewWriter(&buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			log.WithError(err).Error("encodeBase64: could not create zstd writer")
+			return "", err
+		}
+		comw = zw
+	case "":
+		comw = nopWriteCloser{&buf}
+	default:
+		log.WithError(ErrUnknownCompression).WithField("Compression", compression).Error("encodeBase64: unable to handle this compression type")
+		return "", ErrUnknownCompression
+	}
+
+	if err := binary.Write(comw, binary.LittleEndian, gids); err != nil {
+		log.WithError(err).Error("encodeBase64: could not write data")
+		return "", err
+	}
+	if err := comw.Close(); err != nil {
+		log.WithError(err).Error("encodeBase64: could not close compression writer")
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func toTMXProperties(ps []*Property) *tmxProperties {
+	if len(ps) == 0 {
+		return nil
+	}
+
+	tps := &tmxProperties{}
+	for _, p := range ps {
+		tps.Properties = append(tps.Properties, &tmxProperty{
+			Name:         p.Name,
+			Type:         p.Type,
+			PropertyType: p.PropertyType,
+			Value:        p.Value,
+			Properties:   toTMXProperties(p.Properties),
+		})
+	}
+	return tps
+}
+
+// toTMXText converts the text of an object, omitting the attributes which are the default values in Tiled.
+func toTMXText(t *Text) *tmxText {
+	if t == nil {
+		return nil
+	}
+
+	def := defaultText()
+	tt := &tmxText{
+		Text:      t.Text,
+		Wrap:      boolAttr(t.Wrap),
+		Color:     t.Color,
+		Bold:      boolAttr(t.Bold),
+		Italic:    boolAttr(t.Italic),
+		Underline: boolAttr(t.Underline),
+		Strikeout: boolAttr(t.Strikeout),
+	}
+	if t.FontFamily != def.FontFamily {
+		tt.FontFamily = t.FontFamily
+	}
+	if t.PixelSize != def.PixelSize {
+		tt.PixelSize = t.PixelSize
+	}
+	if !t.Kerning {
+		tt.Kerning = "0"
+	}
+	if t.HAlign != def.HAlign {
+		tt.HAlign = t.HAlign
+	}
+	if t.VAlign != def.VAlign {
+		tt.VAlign = t.VAlign
+	}
+	return tt
+}
+
+func toTMXImage(i *Image) *tmxImage {
+	if i == nil {
+		return nil
+	}
+	ti := &tmxImage{Format: i.Format, Source: i.Source, Trans: i.Trans, Width: i.Width, Height: i.Height}
+	if i.Data != nil {
+		// Embedded images are written as they were read, the data is not re-encoded.
+		ti.Data = &tmxData{Encoding: i.Data.Encoding, Compression: i.Data.Compression, Data: string(bytes.TrimSpace(i.Data.RawData))}
+	}
+	return ti
+}
+
+// boolAttr returns the value Tiled uses for a true boolean attribute, or an empty string (so the attribute is omitted)
+// when false.
+func boolAttr(b bool) string {
+	if b {
+		return "1"
+	}
+	return ""
+}
+
+// opacityAttr returns the value of an opacity attribute, or an empty string (so the attribute is omitted) when fully
+// opaque, as this is the default in Tiled.
+func opacityAttr(o float64) string {
+	if o == 1 {
+		return ""
+	}
+	return strconv.FormatFloat(o, 'f', -1, 32)
+}
+
+// parallaxAttr returns the value of a parallax factor attribute, or an empty string (so the attribute is omitted) when
+// the factor is 1, as this is the default in Tiled.
+func parallaxAttr(f float64) string {
+	if f == 1 {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// visibleAttr returns the value of a visible attribute, or an empty string (so the attribute is omitted) when visible,
+// as this is the default in Tiled.
+func visibleAttr(v bool) string {
+	if v {
+		return ""
+	}
+	return "0"
+}
+
+// floorTo rounds n down to the nearest multiple of m.
+func floorTo(n, m int) int {
+	if n < 0 && n%m != 0 {
+		return n - n%m - m
+	}
+	return n - n%m
+}
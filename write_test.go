@@ -0,0 +1,160 @@
+package tilepix_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bcvery1/tilepix"
+)
+
+func TestMap_Write(t *testing.T) {
+	files := []string{
+		"testdata/poly.tmx",
+		"testdata/ellipse.tmx",
+		"testdata/external_tileset.tmx",
+		"testdata/tileobjectgroups.tmx",
+		"testdata/infinite-chunks.tmx",
+		"testdata/properties.tmx",
+		"testdata/animation.tmx",
+		"testdata/groups.tmx",
+		"testdata/collection.tmx",
+		"testdata/isometric.tmx",
+		"testdata/hexagonal.tmx",
+		"testdata/staggered.tmx",
+		"testdata/templates.tmx",
+		"testdata/text.tmx",
+		"testdata/embedded.tmx",
+		"testdata/tileoffset.tmx",
+		"testdata/parallax.tmx",
+		"testdata/multiple_tilesets.tmx",
+		"testdata/poly.tmj",
+	}
+	options := []struct {
+		name string
+		opts *tilepix.WriteOptions
+	}{
+		{name: "default", opts: nil},
+		{name: "xml", opts: &tilepix.WriteOptions{Encoding: "xml"}},
+		{name: "base64", opts: &tilepix.WriteOptions{Encoding: "base64"}},
+		{name: "base64-gzip", opts: &tilepix.WriteOptions{Encoding: "base64", Compression: "gzip"}},
+		{name: "base64-zlib", opts: &tilepix.WriteOptions{Encoding: "base64", Compression: "zlib"}},
+		{name: "base64-zstd", opts: &tilepix.WriteOptions{Encoding: "base64", Compression: "zstd"}},
+	}
+
+	for _, f := range files {
+		for _, o := range options {
+			t.Run(f+"/"+o.name, func(t *testing.T) {
+				want, err := tilepix.ReadFile(f)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var buf bytes.Buffer
+				if err := want.Write(&buf, o.opts); err != nil {
+					t.Fatalf("Could not write map: %v", err)
+				}
+
+				got, err := tilepix.Read(&buf, "testdata", nil)
+				if err != nil {
+					t.Fatalf("Could not read written map: %v", err)
+				}
+
+				if len(got.TileLayers) != len(want.TileLayers) || len(got.ObjectGroups) != len(want.ObjectGroups) || len(got.ImageLayers) != len(want.ImageLayers) {
+					t.Fatalf("Layer count mismatch, expected %v, got %v", want, got)
+				}
+				compareMaps(t, want, got)
+
+				for _, wo := range want.GetObjectByName("Polygon") {
+					wp, _ := wo.GetPolygon()
+					gp, err := got.GetObjectByName("Polygon")[0].GetPolygon()
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !reflect.DeepEqual(gp, wp) {
+						t.Errorf("Polygon mismatch, expected %v, got %v", wp, gp)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestMap_Write_modified(t *testing.T) {
+	m, err := tilepix.ReadFile("testdata/external_tileset.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flip the first tile and clear the second.
+	l := m.TileLayers[0]
+	l.DecodedTiles[0].HorizontalFlip = true
+	l.DecodedTiles[1] = tilepix.NilTile
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf, nil); err != nil {
+		t.Fatalf("Could not write map: %v", err)
+	}
+
+	got, err := tilepix.Read(&buf, "testdata", nil)
+	if err != nil {
+		t.Fatalf("Could not read written map: %v", err)
+	}
+
+	if ts := got.Tilesets[0]; ts.Source != "tileset.tsx" || ts.Name != "tileset" {
+		t.Errorf("Expected external tileset reference to be kept, got %v", ts)
+	}
+
+	gl := got.TileLayers[0]
+	if dt := gl.DecodedTiles[0]; dt.ID != l.DecodedTiles[0].ID || !dt.HorizontalFlip {
+		t.Errorf("Expected first tile to be flipped, got %v", dt)
+	}
+	if !gl.DecodedTiles[1].IsNil() {
+		t.Errorf("Expected second tile to be nil, got %v", gl.DecodedTiles[1])
+	}
+}
+
+func TestMap_Write_defaults(t *testing.T) {
+	// None of the layers set visible or opacity, so these take Tiled's defaults.
+	tmx := `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.2" orientation="orthogonal" renderorder="right-down" width="1" height="1" tilewidth="16" tileheight="16">
+ <layer name="Tiles" width="1" height="1">
+  <data encoding="csv">0</data>
+ </layer>
+ <objectgroup name="Objects"/>
+ <imagelayer name="Image"/>
+ <group name="Group"/>
+</map>`
+
+	m, err := tilepix.Read(strings.NewReader(tmx), "testdata", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf, nil); err != nil {
+		t.Fatalf("Could not write map: %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "visible=") || strings.Contains(out, "opacity=") {
+		t.Errorf("Expected the default visibility and opacity to be omitted, got %s", out)
+	}
+
+	got, err := tilepix.Read(&buf, "testdata", nil)
+	if err != nil {
+		t.Fatalf("Could not read written map: %v", err)
+	}
+
+	if l := got.TileLayers[0]; !l.Visible || l.Opacity != 1 {
+		t.Errorf("Expected tile layer to be visible with opacity 1, got %v %v", l.Visible, l.Opacity)
+	}
+	if og := got.ObjectGroups[0]; !og.Visible || og.Opacity != 1 {
+		t.Errorf("Expected object group to be visible with opacity 1, got %v %v", og.Visible, og.Opacity)
+	}
+	if il := got.ImageLayers[0]; !il.Visible || il.Opacity != 1 {
+		t.Errorf("Expected image layer to be visible with opacity 1, got %v %v", il.Visible, il.Opacity)
+	}
+	if g := got.Groups[0]; !g.Visible || g.Opacity != 1 {
+		t.Errorf("Expected group to be visible with opacity 1, got %v %v", g.Visible, g.Opacity)
+	}
+}